@@ -0,0 +1,204 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// convertBlockFrames is how many input frames Convert decodes at a time.
+const convertBlockFrames = 1024
+
+// Convert returns a reader yielding src, which holds samples in format from,
+// as 16-bit PCM in format to. It changes bit depth and encoding, mixes or
+// duplicates channels and resamples. Resampling interpolates linearly between
+// frames, which is plenty for speech; there is no anti-aliasing filter, so
+// downsampling music from high rates will sound harsh.
+func Convert(src io.Reader, from, to Format) (io.Reader, error) {
+	if err := from.validate(); err != nil {
+		return nil, err
+	}
+	if err := to.validate(); err != nil {
+		return nil, err
+	}
+	if to.Encoding != EncodingPCM || to.BitsPerSample != 16 {
+		return nil, fmt.Errorf("%w: can only convert to 16-bit PCM, not %s", ErrUnsupportedFormat, to)
+	}
+	if from == to {
+		return src, nil
+	}
+	return &converter{
+		src:  src,
+		from: from,
+		to:   to,
+		step: float64(from.SampleRate) / float64(to.SampleRate),
+		in:   make([]byte, convertBlockFrames*from.FrameSize()),
+	}, nil
+}
+
+type converter struct {
+	src      io.Reader
+	from, to Format
+	step     float64 // input frames per output frame
+
+	in      []byte // undecoded input, including a partial frame from the last read
+	pending int    // bytes of in holding a partial frame
+	frames  [][]float32
+	pos     float64 // position of the next output frame in frames
+	eof     bool
+	out     []byte // encoded output not yet returned
+}
+
+func (c *converter) Read(p []byte) (int, error) {
+	for len(c.out) == 0 {
+		if c.eof {
+			return 0, io.EOF
+		}
+		if err := c.fill(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.out)
+	c.out = c.out[n:]
+	return n, nil
+}
+
+// fill reads a block of input and converts as much of it as possible.
+func (c *converter) fill() error {
+	n, err := io.ReadAtLeast(c.src, c.in[c.pending:], 1)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		c.eof = true
+	} else if err != nil {
+		return err
+	}
+	n += c.pending
+
+	frameSize := c.from.FrameSize()
+	whole := n / frameSize * frameSize
+	for off := 0; off < whole; off += frameSize {
+		c.frames = append(c.frames, c.mapChannels(c.decodeFrame(c.in[off:off+frameSize])))
+	}
+	// Keep a frame split across reads for next time; a trailing partial
+	// frame at the end of the stream is dropped
+	c.pending = copy(c.in, c.in[whole:n])
+
+	c.resample()
+	return nil
+}
+
+// resample emits output frames between the buffered input frames. The last
+// input frame is kept back until the next block arrives, since output frames
+// after it interpolate towards its successor.
+func (c *converter) resample() {
+	for {
+		i := int(c.pos)
+		if i+1 >= len(c.frames) {
+			if !c.eof || i >= len(c.frames) {
+				break
+			}
+			c.emit(c.frames[i])
+		} else {
+			frac := float32(c.pos - float64(i))
+			mixed := make([]float32, c.to.Channels)
+			for ch := range mixed {
+				mixed[ch] = c.frames[i][ch]*(1-frac) + c.frames[i+1][ch]*frac
+			}
+			c.emit(mixed)
+		}
+		c.pos += c.step
+	}
+
+	// Drop the frames no later output can reach
+	if drop := int(c.pos); drop > 0 {
+		if drop > len(c.frames) {
+			drop = len(c.frames)
+		}
+		c.frames = append(c.frames[:0], c.frames[drop:]...)
+		c.pos -= float64(drop)
+	}
+}
+
+func (c *converter) emit(frame []float32) {
+	for _, v := range frame {
+		c.out = binary.LittleEndian.AppendUint16(c.out, uint16(floatToS16(v)))
+	}
+}
+
+// decodeFrame returns one frame's samples scaled to [-1, 1].
+func (c *converter) decodeFrame(b []byte) []float32 {
+	frame := make([]float32, c.from.Channels)
+	width := c.from.BitsPerSample / 8
+	for ch := range frame {
+		s := b[ch*width : (ch+1)*width]
+		switch {
+		case c.from.Encoding == EncodingFloat && width == 4:
+			frame[ch] = clip(math.Float32frombits(binary.LittleEndian.Uint32(s)))
+		case c.from.Encoding == EncodingFloat:
+			frame[ch] = clip(float32(math.Float64frombits(binary.LittleEndian.Uint64(s))))
+		case width == 1:
+			// 8-bit WAV samples are unsigned
+			frame[ch] = float32(int(s[0])-128) / 128
+		case width == 2:
+			frame[ch] = float32(int16(binary.LittleEndian.Uint16(s))) / (1 << 15)
+		case width == 3:
+			v := int32(s[0]) | int32(s[1])<<8 | int32(int8(s[2]))<<16
+			frame[ch] = float32(v) / (1 << 23)
+		case width == 4:
+			frame[ch] = float32(int32(binary.LittleEndian.Uint32(s))) / (1 << 31)
+		}
+	}
+	return frame
+}
+
+// mapChannels converts a frame to the output channel count. Mono is copied
+// to every channel and anything going to mono is averaged; otherwise
+// channels are matched up by position and extra ones dropped or left silent.
+func (c *converter) mapChannels(frame []float32) []float32 {
+	if len(frame) == c.to.Channels {
+		return frame
+	}
+	out := make([]float32, c.to.Channels)
+	switch {
+	case len(frame) == 1:
+		for ch := range out {
+			out[ch] = frame[0]
+		}
+	case len(out) == 1:
+		var sum float32
+		for _, v := range frame {
+			sum += v
+		}
+		out[0] = sum / float32(len(frame))
+	default:
+		copy(out, frame)
+	}
+	return out
+}
+
+// clip limits a float sample to [-1, 1] and silences NaN, so out of range
+// input cannot spill into neighbouring samples when interpolating.
+func clip(v float32) float32 {
+	switch {
+	case v != v: // NaN
+		return 0
+	case v > 1:
+		return 1
+	case v < -1:
+		return -1
+	}
+	return v
+}
+
+func floatToS16(v float32) int16 {
+	if v != v { // NaN
+		return 0
+	}
+	if v >= 1 {
+		return math.MaxInt16
+	}
+	if v <= -1 {
+		return -math.MaxInt16
+	}
+	return int16(math.Round(float64(v) * math.MaxInt16))
+}
@@ -0,0 +1,195 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+	"testing"
+)
+
+// sine returns seconds of a 440Hz tone at amplitude 0.5 in f.
+func sine(f Format, seconds float64) []byte {
+	var b []byte
+	n := int(seconds * float64(f.SampleRate))
+	for i := 0; i < n; i++ {
+		v := 0.5 * math.Sin(2*math.Pi*440*float64(i)/float64(f.SampleRate))
+		for ch := 0; ch < f.Channels; ch++ {
+			b = appendSample(b, f, v)
+		}
+	}
+	return b
+}
+
+func appendSample(b []byte, f Format, v float64) []byte {
+	switch {
+	case f.Encoding == EncodingFloat && f.BitsPerSample == 32:
+		return binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(v)))
+	case f.Encoding == EncodingFloat:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+	case f.BitsPerSample == 8:
+		return append(b, byte(int(math.Round(v*127))+128))
+	case f.BitsPerSample == 16:
+		return binary.LittleEndian.AppendUint16(b, uint16(int16(math.Round(v*math.MaxInt16))))
+	case f.BitsPerSample == 24:
+		s := int32(math.Round(v * (1<<23 - 1)))
+		return append(b, byte(s), byte(s>>8), byte(s>>16))
+	default:
+		return binary.LittleEndian.AppendUint32(b, uint32(int32(math.Round(v*math.MaxInt32))))
+	}
+}
+
+// convertAll runs src through Convert, reading in awkward chunk sizes so
+// frames are split across reads.
+func convertAll(t *testing.T, src []byte, from, to Format) []int16 {
+	t.Helper()
+	r, err := Convert(&oddReader{r: bytes.NewReader(src)}, from, to)
+	if err != nil {
+		t.Fatalf("Convert: %v", err)
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("reading converted audio: %v", err)
+	}
+	if len(out)%to.FrameSize() != 0 {
+		t.Fatalf("output is %d bytes, not a whole number of %d-byte frames", len(out), to.FrameSize())
+	}
+	samples := make([]int16, len(out)/2)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(out[i*2:]))
+	}
+	return samples
+}
+
+// oddReader returns at most 333 bytes per read.
+type oddReader struct{ r io.Reader }
+
+func (o *oddReader) Read(p []byte) (int, error) {
+	if len(p) > 333 {
+		p = p[:333]
+	}
+	return o.r.Read(p)
+}
+
+// zeroCrossings counts sign changes in one channel of interleaved samples.
+func zeroCrossings(samples []int16, channels, ch int) int {
+	n := 0
+	for i := ch + channels; i < len(samples); i += channels {
+		if (samples[i-channels] < 0) != (samples[i] < 0) {
+			n++
+		}
+	}
+	return n
+}
+
+func peak(samples []int16) int {
+	p := 0
+	for _, s := range samples {
+		p = max(p, int(math.Abs(float64(s))))
+	}
+	return p
+}
+
+func TestConvertToMic(t *testing.T) {
+	mic := Format{SampleRate: 48000, Channels: 2, BitsPerSample: 16, Encoding: EncodingPCM}
+
+	sources := []Format{
+		{SampleRate: 22050, Channels: 1, BitsPerSample: 16, Encoding: EncodingPCM},
+		{SampleRate: 16000, Channels: 1, BitsPerSample: 8, Encoding: EncodingPCM},
+		{SampleRate: 44100, Channels: 2, BitsPerSample: 24, Encoding: EncodingPCM},
+		{SampleRate: 24000, Channels: 1, BitsPerSample: 32, Encoding: EncodingPCM},
+		{SampleRate: 24000, Channels: 1, BitsPerSample: 32, Encoding: EncodingFloat},
+		{SampleRate: 96000, Channels: 2, BitsPerSample: 64, Encoding: EncodingFloat},
+		mic,
+	}
+	for _, from := range sources {
+		t.Run(from.String(), func(t *testing.T) {
+			samples := convertAll(t, sine(from, 1), from, mic)
+
+			// One second in is one second out, give or take a frame
+			frames := len(samples) / mic.Channels
+			if frames < mic.SampleRate-2 || frames > mic.SampleRate+2 {
+				t.Errorf("got %d frames, want about %d", frames, mic.SampleRate)
+			}
+
+			// 440Hz crosses zero 880 times a second
+			for ch := 0; ch < mic.Channels; ch++ {
+				if n := zeroCrossings(samples, mic.Channels, ch); n < 878 || n > 881 {
+					t.Errorf("channel %d crosses zero %d times, want about 880", ch, n)
+				}
+			}
+
+			if p := peak(samples); math.Abs(float64(p)/math.MaxInt16-0.5) > 0.01 {
+				t.Errorf("peak = %d, want about half of full scale", p)
+			}
+		})
+	}
+}
+
+func TestConvertMonoToStereo(t *testing.T) {
+	from := Format{SampleRate: 48000, Channels: 1, BitsPerSample: 16, Encoding: EncodingPCM}
+	to := Format{SampleRate: 48000, Channels: 2, BitsPerSample: 16, Encoding: EncodingPCM}
+
+	// A ramp makes any misplaced sample show up
+	var src []byte
+	for i := -1000; i < 1000; i++ {
+		src = binary.LittleEndian.AppendUint16(src, uint16(int16(i*16)))
+	}
+
+	samples := convertAll(t, src, from, to)
+	if len(samples) != 2*2000 {
+		t.Fatalf("got %d samples, want %d", len(samples), 2*2000)
+	}
+	for i := 0; i < len(samples); i += 2 {
+		want := int16((i/2 - 1000) * 16)
+		if samples[i] != want || samples[i+1] != want {
+			t.Fatalf("frame %d = %d, %d; want %d on both channels", i/2, samples[i], samples[i+1], want)
+		}
+	}
+}
+
+func TestConvertStereoToMono(t *testing.T) {
+	from := Format{SampleRate: 8000, Channels: 2, BitsPerSample: 16, Encoding: EncodingPCM}
+	to := Format{SampleRate: 8000, Channels: 1, BitsPerSample: 16, Encoding: EncodingPCM}
+
+	var src []byte
+	for i := 0; i < 100; i++ {
+		src = binary.LittleEndian.AppendUint16(src, uint16(int16(1000)))
+		src = binary.LittleEndian.AppendUint16(src, uint16(int16(3000)))
+	}
+
+	for i, s := range convertAll(t, src, from, to) {
+		if s != 2000 {
+			t.Fatalf("sample %d = %d, want the average 2000", i, s)
+		}
+	}
+}
+
+func TestConvertClips(t *testing.T) {
+	from := Format{SampleRate: 48000, Channels: 1, BitsPerSample: 32, Encoding: EncodingFloat}
+	to := Format{SampleRate: 48000, Channels: 1, BitsPerSample: 16, Encoding: EncodingPCM}
+
+	var src []byte
+	for _, v := range []float64{2, -2, 1, -1, math.Inf(1), math.Inf(-1), math.NaN(), 0.5} {
+		src = appendSample(src, from, v)
+	}
+
+	got := convertAll(t, src, from, to)
+	want := []int16{math.MaxInt16, -math.MaxInt16, math.MaxInt16, -math.MaxInt16, math.MaxInt16, -math.MaxInt16, 0, 16384}
+	if len(got) != len(want) {
+		t.Fatalf("got %d samples, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("sample %d = %d, want %d", i, got[i], want[i])
+		}
+	}
+}
+
+func TestConvertRejectsUnsupportedOutput(t *testing.T) {
+	from := Format{SampleRate: 22050, Channels: 1, BitsPerSample: 16, Encoding: EncodingPCM}
+	to := Format{SampleRate: 48000, Channels: 2, BitsPerSample: 24, Encoding: EncodingPCM}
+	if _, err := Convert(bytes.NewReader(nil), from, to); err == nil {
+		t.Error("Convert to 24-bit succeeded, want an error")
+	}
+}
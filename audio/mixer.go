@@ -0,0 +1,300 @@
+package audio
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+var ErrMixerClosed = errors.New("mixer closed")
+
+const (
+	// trackBuffer is how many frames of a track are read ahead of playback.
+	trackBuffer = 50
+	// writeStallTimeout bounds a write to a pipe nobody is reading.
+	writeStallTimeout = time.Second
+	reopenInterval    = time.Second
+)
+
+// Mixer is the only writer to a virtual mic pipe. It writes a continuous
+// stream at the rate it is played: silence while idle and tracks passed to
+// Play, one after another, while they last. Pacing keeps the pipe short, so
+// a track can be stopped promptly and Play knows when it has been heard.
+type Mixer struct {
+	format    Format
+	frameSize int // bytes per FrameDuration
+	open      func() (io.WriteCloser, error)
+
+	mu      sync.Mutex
+	queue   []*track
+	current *track
+	closed  bool
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+type track struct {
+	frames   chan []byte
+	readErr  error // set before frames is closed
+	playback Playback
+	started  bool
+	err      error
+	done     chan struct{} // closed once the track has been heard or dropped
+}
+
+// NewMixer starts writing format to the pipe returned by open. The pipe is
+// reopened if writing fails, e.g. while PulseAudio restarts.
+func NewMixer(format Format, open func() (io.WriteCloser, error)) *Mixer {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Mixer{
+		format:    format,
+		frameSize: frameBytes(format),
+		open:      open,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go m.run(ctx)
+	return m
+}
+
+// Play queues r, which holds PCM in the mixer's format, behind any tracks
+// already waiting and returns once it has finished playing. If ctx is done
+// first the track is cut off or dropped from the queue, and Play returns
+// ctx.Err() with what had been played so far.
+//
+// The mixer owns r: it is closed once it has been read to the end or, if
+// the track is cut off, after the read in progress returns. The caller must
+// not close it.
+func (m *Mixer) Play(ctx context.Context, r io.ReadCloser) (Playback, error) {
+	t := &track{
+		frames: make(chan []byte, trackBuffer),
+		done:   make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	if m.closed {
+		m.mu.Unlock()
+		r.Close()
+		return Playback{}, ErrMixerClosed
+	}
+	m.queue = append(m.queue, t)
+	m.mu.Unlock()
+
+	go m.feed(t, r)
+
+	select {
+	case <-t.done:
+	case <-ctx.Done():
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if t.err == nil && ctx.Err() != nil {
+		m.dropLocked(t, ctx.Err())
+	}
+	return t.playback, t.err
+}
+
+// feed reads r into frames ahead of the mixer and closes it when done.
+func (m *Mixer) feed(t *track, r io.ReadCloser) {
+	defer r.Close()
+	defer close(t.frames)
+	for {
+		buf := make([]byte, m.frameSize)
+		n, err := io.ReadFull(r, buf)
+		n -= n % m.format.FrameSize()
+		if n > 0 {
+			select {
+			case t.frames <- buf[:n]:
+			case <-t.done:
+				return
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return
+		}
+		if err != nil {
+			t.readErr = fmt.Errorf("failed to read audio: %v", err)
+			return
+		}
+	}
+}
+
+// Close stops the mixer. Tracks still playing or queued fail with
+// ErrMixerClosed.
+func (m *Mixer) Close() {
+	m.mu.Lock()
+	m.closed = true
+	m.mu.Unlock()
+
+	m.cancel()
+	<-m.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current != nil {
+		m.dropLocked(m.current, ErrMixerClosed)
+	}
+	for len(m.queue) > 0 {
+		m.dropLocked(m.queue[0], ErrMixerClosed)
+	}
+}
+
+func (m *Mixer) run(ctx context.Context) {
+	defer close(m.done)
+
+	var pipe io.WriteCloser
+	defer func() {
+		if pipe != nil {
+			pipe.Close()
+		}
+	}()
+
+	silence := make([]byte, m.frameSize)
+	pace := newPacer(pacedLead, time.Now())
+	openFailed := false
+
+	for {
+		if pipe == nil {
+			var err error
+			pipe, err = m.open()
+			if err != nil {
+				if !openFailed {
+					log.Printf("[MIXER] Failed to open pipe, retrying: %v", err)
+					openFailed = true
+				}
+				pipe = nil
+				if sleep(ctx, reopenInterval) != nil {
+					return
+				}
+				continue
+			}
+			openFailed = false
+			pace.reset(time.Now())
+		}
+
+		due, wait, _ := pace.next(time.Now())
+		if sleep(ctx, wait) != nil {
+			return
+		}
+
+		frame := m.nextFrame(due)
+		if frame == nil {
+			frame = silence
+		}
+
+		n, err := writeFrame(ctx, pipe, frame)
+		pace.advance(m.format.Duration(n))
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[MIXER] Failed to write to pipe, reopening: %v", err)
+			pipe.Close()
+			pipe = nil
+		}
+	}
+}
+
+// writeFrame writes frame to the pipe and returns how many bytes went in. A
+// frame the reader takes none of within writeStallTimeout is dropped whole.
+// Once part of a frame is in the pipe the rest has to follow, however long
+// that takes, or every later sample would be out of alignment.
+func writeFrame(ctx context.Context, pipe io.Writer, frame []byte) (int, error) {
+	deadline, _ := pipe.(interface{ SetWriteDeadline(time.Time) error })
+
+	sent := 0
+	for sent < len(frame) {
+		if deadline != nil {
+			deadline.SetWriteDeadline(time.Now().Add(writeStallTimeout))
+		}
+		n, err := pipe.Write(frame[sent:])
+		sent += n
+		switch {
+		case err == nil:
+		case !errors.Is(err, os.ErrDeadlineExceeded):
+			return sent, err
+		case sent == 0:
+			return 0, nil
+		case ctx.Err() != nil:
+			return sent, ctx.Err()
+		}
+	}
+	return sent, nil
+}
+
+// nextFrame returns the next frame of the current track, moving on to the
+// next queued track when it runs out, or nil for silence. due is when the
+// frame will be heard.
+func (m *Mixer) nextFrame(due time.Time) []byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for {
+		if m.current == nil {
+			if len(m.queue) == 0 {
+				return nil
+			}
+			m.current, m.queue = m.queue[0], m.queue[1:]
+		}
+		t := m.current
+
+		select {
+		case frame, ok := <-t.frames:
+			if !ok {
+				// Everything before this frame has been written; the track
+				// ends when the listener gets here
+				m.current = nil
+				err := t.readErr
+				time.AfterFunc(time.Until(due), func() { m.finish(t, err) })
+				continue
+			}
+			t.started = true
+			t.playback.Frames++
+			t.playback.Duration += m.format.Duration(len(frame))
+			return frame
+		default:
+			// The track is still being produced; fill the gap with silence
+			if t.started {
+				t.playback.Underruns++
+			}
+			return nil
+		}
+	}
+}
+
+func (m *Mixer) finish(t *track, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.endLocked(t, err)
+}
+
+// dropLocked removes a track that has not finished playing.
+func (m *Mixer) dropLocked(t *track, err error) {
+	if m.current == t {
+		m.current = nil
+	}
+	for i, queued := range m.queue {
+		if queued == t {
+			m.queue = append(m.queue[:i], m.queue[i+1:]...)
+			break
+		}
+	}
+	m.endLocked(t, err)
+}
+
+func (m *Mixer) endLocked(t *track, err error) {
+	select {
+	case <-t.done:
+		return
+	default:
+	}
+	t.err = err
+	close(t.done)
+}
@@ -0,0 +1,320 @@
+package audio
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var mixerFormat = Format{SampleRate: 8000, Channels: 1, BitsPerSample: 16, Encoding: EncodingPCM}
+
+// memPipe stands in for the virtual mic pipe.
+type memPipe struct {
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (p *memPipe) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.buf = append(p.buf, b...)
+	return len(b), nil
+}
+
+func (p *memPipe) Close() error { return nil }
+
+func (p *memPipe) bytes() []byte {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return bytes.Clone(p.buf)
+}
+
+// frames counts the bytes of the pipe holding v, in frames.
+func (p *memPipe) frames(v byte) int {
+	return bytes.Count(p.bytes(), []byte{v}) / frameBytes(mixerFormat)
+}
+
+// trackReader is a track that records being closed.
+type trackReader struct {
+	io.Reader
+	closed atomic.Bool
+}
+
+func (r *trackReader) Close() error {
+	r.closed.Store(true)
+	return nil
+}
+
+// tone returns frames frames of PCM with every byte set to v, so a track
+// can be told apart from silence and other tracks in the pipe.
+func tone(frames int, v byte) *trackReader {
+	return &trackReader{Reader: bytes.NewReader(bytes.Repeat([]byte{v}, frames*frameBytes(mixerFormat)))}
+}
+
+func newTestMixer(t *testing.T) (*Mixer, *memPipe) {
+	pipe := &memPipe{}
+	m := NewMixer(mixerFormat, func() (io.WriteCloser, error) { return pipe, nil })
+	t.Cleanup(m.Close)
+	return m, pipe
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestMixerPacing(t *testing.T) {
+	_, pipe := newTestMixer(t)
+
+	time.Sleep(300 * time.Millisecond)
+
+	// 300ms of silence plus the lead, give or take scheduling
+	got := len(pipe.bytes()) / frameBytes(mixerFormat)
+	if got < 12 || got > 24 {
+		t.Errorf("wrote %d frames in 300ms, want about %d", got, (300*time.Millisecond+pacedLead)/FrameDuration)
+	}
+}
+
+func TestMixerPlay(t *testing.T) {
+	m, pipe := newTestMixer(t)
+
+	r := tone(10, 1)
+	start := time.Now()
+	playback, err := m.Play(context.Background(), r)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+
+	if playback.Frames != 10 || playback.Duration != 10*FrameDuration || playback.Underruns != 0 {
+		t.Errorf("Play() = %+v, want 10 frames of %v and no underruns", playback, 10*FrameDuration)
+	}
+	// Play returns once the track has been heard, not once it is in the pipe
+	if elapsed < 10*FrameDuration {
+		t.Errorf("Play() returned after %v, before the track could have been heard", elapsed)
+	}
+	if !r.closed.Load() {
+		t.Error("track was not closed")
+	}
+
+	out := pipe.bytes()
+	first := bytes.IndexByte(out, 1)
+	if first < 0 || first%frameBytes(mixerFormat) != 0 {
+		t.Fatalf("track starts at byte %d, want a frame boundary", first)
+	}
+	if want := bytes.Repeat([]byte{1}, 10*frameBytes(mixerFormat)); !bytes.HasPrefix(out[first:], want) {
+		t.Error("track was not written in one piece")
+	}
+}
+
+func TestMixerQueue(t *testing.T) {
+	m, pipe := newTestMixer(t)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if _, err := m.Play(context.Background(), tone(5, 1)); err != nil {
+			t.Errorf("first Play() error = %v", err)
+		}
+	}()
+	waitFor(t, "the first track to start", func() bool { return pipe.frames(1) > 0 })
+
+	if _, err := m.Play(context.Background(), tone(5, 2)); err != nil {
+		t.Fatalf("second Play() error = %v", err)
+	}
+	wg.Wait()
+
+	out := pipe.bytes()
+	if last, first := bytes.LastIndexByte(out, 1), bytes.IndexByte(out, 2); first < last {
+		t.Errorf("second track started at byte %d, before the first ended at %d", first, last)
+	}
+}
+
+func TestMixerUnderrun(t *testing.T) {
+	m, _ := newTestMixer(t)
+
+	pr, pw := io.Pipe()
+	go func() {
+		frame := bytes.Repeat([]byte{1}, frameBytes(mixerFormat))
+		pw.Write(frame)
+		pw.Write(frame)
+		time.Sleep(150 * time.Millisecond)
+		pw.Write(frame)
+		pw.Close()
+	}()
+
+	playback, err := m.Play(context.Background(), pr)
+	if err != nil {
+		t.Fatalf("Play() error = %v", err)
+	}
+	if playback.Frames != 3 {
+		t.Errorf("Frames = %d, want 3", playback.Frames)
+	}
+	if playback.Underruns < 2 {
+		t.Errorf("Underruns = %d, want the 150ms gap counted", playback.Underruns)
+	}
+}
+
+func TestMixerCancel(t *testing.T) {
+	m, pipe := newTestMixer(t)
+
+	// A track that never ends
+	r := &trackReader{Reader: repeatByte(1)}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	playback, err := m.Play(ctx, r)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Play() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if playback.Frames == 0 || playback.Frames > 20 {
+		t.Errorf("Frames = %d, want what played in 100ms", playback.Frames)
+	}
+	waitFor(t, "the track to be closed", r.closed.Load)
+
+	played := pipe.frames(1)
+	time.Sleep(100 * time.Millisecond)
+	if got := pipe.frames(1); got != played {
+		t.Errorf("%d more frames of the track were written after it was cut off", got-played)
+	}
+
+	// The mixer carries on with the next track
+	if _, err := m.Play(context.Background(), tone(2, 2)); err != nil {
+		t.Fatalf("Play() after cancel error = %v", err)
+	}
+}
+
+func TestMixerDropQueued(t *testing.T) {
+	m, pipe := newTestMixer(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := m.Play(context.Background(), tone(10, 1)); err != nil {
+			t.Errorf("first Play() error = %v", err)
+		}
+	}()
+	waitFor(t, "the first track to start", func() bool { return pipe.frames(1) > 0 })
+
+	queued := tone(5, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	playback, err := m.Play(ctx, queued)
+	if !errors.Is(err, context.Canceled) || playback.Frames != 0 {
+		t.Fatalf("Play() = %+v, %v, want nothing played and %v", playback, err, context.Canceled)
+	}
+	waitFor(t, "the dropped track to be closed", queued.closed.Load)
+
+	<-done
+	if got := pipe.frames(2); got != 0 {
+		t.Errorf("%d frames of the dropped track were written", got)
+	}
+}
+
+func TestMixerClose(t *testing.T) {
+	m, _ := newTestMixer(t)
+
+	r := &trackReader{Reader: repeatByte(1)}
+	errc := make(chan error, 1)
+	go func() {
+		_, err := m.Play(context.Background(), r)
+		errc <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	m.Close()
+
+	if err := <-errc; !errors.Is(err, ErrMixerClosed) {
+		t.Errorf("playing track error = %v, want %v", err, ErrMixerClosed)
+	}
+
+	after := tone(1, 1)
+	if _, err := m.Play(context.Background(), after); !errors.Is(err, ErrMixerClosed) {
+		t.Errorf("Play() after Close error = %v, want %v", err, ErrMixerClosed)
+	}
+	if !after.closed.Load() {
+		t.Error("track passed to a closed mixer was not closed")
+	}
+}
+
+// repeatByte is an endless stream of v.
+type repeatByte byte
+
+func (r repeatByte) Read(b []byte) (int, error) {
+	for i := range b {
+		b[i] = byte(r)
+	}
+	return len(b), nil
+}
+
+// stallingPipe accepts accept[i] bytes on the i-th write and times out if
+// that is short of what was written.
+type stallingPipe struct {
+	accept []int
+	err    error // returned instead of a timeout once accept runs out
+	buf    []byte
+}
+
+func (p *stallingPipe) Write(b []byte) (int, error) {
+	if len(p.accept) == 0 {
+		return 0, p.err
+	}
+	n := min(p.accept[0], len(b))
+	p.accept = p.accept[1:]
+	p.buf = append(p.buf, b[:n]...)
+	if n < len(b) {
+		return n, os.ErrDeadlineExceeded
+	}
+	return n, nil
+}
+
+func TestWriteFrame(t *testing.T) {
+	frame := []byte("0123456789")
+	errBroken := errors.New("broken pipe")
+
+	tests := []struct {
+		name      string
+		accept    []int
+		err       error
+		cancelled bool
+		wantSent  int
+		wantErr   error
+	}{
+		{name: "whole frame", accept: []int{10}, wantSent: 10},
+		{name: "reader stalls", accept: []int{0}, wantSent: 0},
+		// Once part of the frame is in, the rest follows however long it takes
+		{name: "partial then stall", accept: []int{4, 0, 0, 6}, wantSent: 10},
+		{name: "partial then cancelled", accept: []int{4, 0}, cancelled: true, wantSent: 4, wantErr: context.Canceled},
+		{name: "write fails", accept: []int{4}, err: errBroken, wantSent: 4, wantErr: errBroken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+			pipe := &stallingPipe{accept: tt.accept, err: tt.err}
+
+			sent, err := writeFrame(ctx, pipe, frame)
+			if sent != tt.wantSent || !errors.Is(err, tt.wantErr) {
+				t.Fatalf("writeFrame() = %d, %v, want %d, %v", sent, err, tt.wantSent, tt.wantErr)
+			}
+			if string(pipe.buf) != string(frame[:sent]) {
+				t.Errorf("pipe got %q, want %q", pipe.buf, frame[:sent])
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package audio
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	// FrameDuration is the amount of audio written to a paced pipe at a time.
+	FrameDuration = 20 * time.Millisecond
+	// pacedLead is how far ahead of the listener a paced pipe is kept, so
+	// scheduling jitter does not leave it empty.
+	pacedLead = 60 * time.Millisecond
+)
+
+// Playback reports how playing a track went.
+type Playback struct {
+	Duration  time.Duration // length of the audio played
+	Frames    int
+	Underruns int // frames of silence played because the track was late
+}
+
+// pacer schedules writes to a reader that consumes audio in real time, such
+// as a PulseAudio pipe source, so each frame goes in shortly before the
+// listener reaches it rather than as fast as the pipe accepts it. That keeps
+// the pipe short, so audio can be stopped promptly, and tells the writer
+// when what it wrote has actually been heard.
+type pacer struct {
+	lead    time.Duration
+	start   time.Time
+	written time.Duration
+}
+
+func newPacer(lead time.Duration, now time.Time) *pacer {
+	return &pacer{lead: lead, start: now}
+}
+
+// frameBytes is the size of FrameDuration of audio in format.
+func frameBytes(format Format) int {
+	return format.SampleRate * int(FrameDuration) / int(time.Second) * format.FrameSize()
+}
+
+// reset restarts the schedule at now, e.g. after the pipe was reopened.
+func (p *pacer) reset(now time.Time) {
+	p.start, p.written = now, 0
+}
+
+// next returns when the next frame will be heard and how long to wait before
+// writing it. If the listener has already caught up the pipe ran dry: the
+// schedule carries on from now rather than rushing to catch up, and late is
+// true.
+func (p *pacer) next(now time.Time) (due time.Time, wait time.Duration, late bool) {
+	due = p.start.Add(p.written)
+	if now.After(due) {
+		p.start = now.Add(-p.written)
+		return now, 0, true
+	}
+	return due, max(due.Sub(now)-p.lead, 0), false
+}
+
+// advance records d more audio as written.
+func (p *pacer) advance(d time.Duration) {
+	p.written += d
+}
+
+// sleep waits for d or until ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
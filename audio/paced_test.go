@@ -0,0 +1,64 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPacer(t *testing.T) {
+	start := time.Unix(1000, 0)
+	p := newPacer(pacedLead, start)
+
+	// The first frames go in straight away until the pipe holds the lead
+	for i := 0; i < 4; i++ {
+		due, wait, late := p.next(start)
+		if want := start.Add(time.Duration(i) * FrameDuration); !due.Equal(want) || wait != 0 || late {
+			t.Fatalf("frame %d: next = %v, %v, %v, want %v, 0, false", i, due, wait, late, want)
+		}
+		p.advance(FrameDuration)
+	}
+
+	// Then one frame per FrameDuration, each pacedLead ahead of the listener
+	due, wait, late := p.next(start)
+	if want := start.Add(4 * FrameDuration); !due.Equal(want) || wait != FrameDuration || late {
+		t.Fatalf("next = %v, %v, %v, want %v, %v, false", due, wait, late, want, FrameDuration)
+	}
+	now := start.Add(FrameDuration)
+	if _, wait, _ := p.next(now); wait != 0 {
+		t.Fatalf("wait after sleeping = %v, want 0", wait)
+	}
+	p.advance(FrameDuration)
+
+	// The writer fell behind: the schedule restarts from now
+	now = start.Add(time.Second)
+	due, wait, late = p.next(now)
+	if !due.Equal(now) || wait != 0 || !late {
+		t.Fatalf("late next = %v, %v, %v, want %v, 0, true", due, wait, late, now)
+	}
+	p.advance(FrameDuration)
+	due, wait, late = p.next(now)
+	if want := now.Add(FrameDuration); !due.Equal(want) || wait != 0 || late {
+		t.Fatalf("next after restart = %v, %v, %v, want %v, 0, false", due, wait, late, want)
+	}
+
+	p.reset(now)
+	if due, _, _ := p.next(now); !due.Equal(now) {
+		t.Fatalf("next after reset = %v, want %v", due, now)
+	}
+}
+
+func TestFrameBytes(t *testing.T) {
+	tests := []struct {
+		format Format
+		want   int
+	}{
+		{Format{SampleRate: 48000, Channels: 2, BitsPerSample: 16, Encoding: EncodingPCM}, 3840},
+		{Format{SampleRate: 16000, Channels: 1, BitsPerSample: 16, Encoding: EncodingPCM}, 640},
+		{Format{SampleRate: 22050, Channels: 1, BitsPerSample: 32, Encoding: EncodingFloat}, 1764},
+	}
+	for _, tt := range tests {
+		if got := frameBytes(tt.format); got != tt.want {
+			t.Errorf("frameBytes(%+v) = %d, want %d", tt.format, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrNotWAV            = errors.New("not a RIFF/WAVE file")
+	ErrUnsupportedFormat = errors.New("unsupported WAV format")
+	ErrNoData            = errors.New("WAV file has no data chunk")
+)
+
+// Encoding is how samples are stored.
+type Encoding int
+
+const (
+	EncodingPCM   Encoding = iota // signed integers (unsigned for 8-bit)
+	EncodingFloat                 // IEEE float
+)
+
+func (e Encoding) String() string {
+	if e == EncodingFloat {
+		return "float"
+	}
+	return "pcm"
+}
+
+// Format describes interleaved sample data.
+type Format struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	Encoding      Encoding
+}
+
+func (f Format) String() string {
+	return fmt.Sprintf("%s %d-bit %dHz %dch", f.Encoding, f.BitsPerSample, f.SampleRate, f.Channels)
+}
+
+// FrameSize is the number of bytes holding one sample for every channel.
+func (f Format) FrameSize() int {
+	return f.Channels * f.BitsPerSample / 8
+}
+
+// Duration returns how long n bytes of audio take to play.
+func (f Format) Duration(n int) time.Duration {
+	frames := n / f.FrameSize()
+	return time.Duration(frames) * time.Second / time.Duration(f.SampleRate)
+}
+
+// validate rejects formats the rest of the package cannot handle.
+func (f Format) validate() error {
+	switch {
+	case f.Channels < 1 || f.Channels > 8:
+		return fmt.Errorf("%w: %d channels", ErrUnsupportedFormat, f.Channels)
+	case f.SampleRate < 1000 || f.SampleRate > 384000:
+		return fmt.Errorf("%w: sample rate %dHz", ErrUnsupportedFormat, f.SampleRate)
+	case f.Encoding == EncodingPCM && f.BitsPerSample != 8 && f.BitsPerSample != 16 &&
+		f.BitsPerSample != 24 && f.BitsPerSample != 32:
+		return fmt.Errorf("%w: %d-bit PCM", ErrUnsupportedFormat, f.BitsPerSample)
+	case f.Encoding == EncodingFloat && f.BitsPerSample != 32 && f.BitsPerSample != 64:
+		return fmt.Errorf("%w: %d-bit float", ErrUnsupportedFormat, f.BitsPerSample)
+	}
+	return nil
+}
+
+// WAV format tags from the fmt chunk.
+const (
+	wavFormatPCM        = 0x0001
+	wavFormatFloat      = 0x0003
+	wavFormatExtensible = 0xFFFE
+)
+
+// streamedSize is the data size written by tools that stream WAV to a pipe
+// and cannot seek back to fill in the real length.
+const streamedSize = 0xFFFFFFFF
+
+// WAV is a decoded WAV header with a reader positioned at the sample data.
+type WAV struct {
+	Format Format
+	// Data yields the raw interleaved samples of the data chunk.
+	Data io.Reader
+}
+
+// DecodeWAV reads RIFF chunks from r up to the data chunk. Chunks other than
+// fmt and data (LIST, fact, cue, ...) are skipped. Reading Data continues to
+// read from r, so r must not be used otherwise afterwards.
+func DecodeWAV(r io.Reader) (*WAV, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotWAV, err)
+	}
+	if string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, ErrNotWAV
+	}
+
+	var format *Format
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(r, chunk[:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				if format == nil {
+					return nil, fmt.Errorf("%w: missing fmt chunk", ErrNotWAV)
+				}
+				return nil, ErrNoData
+			}
+			return nil, err
+		}
+		id := string(chunk[0:4])
+		size := binary.LittleEndian.Uint32(chunk[4:8])
+
+		switch id {
+		case "fmt ":
+			if size < 16 || size > 1024 {
+				return nil, fmt.Errorf("%w: fmt chunk of %d bytes", ErrNotWAV, size)
+			}
+			body := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, body); err != nil {
+				return nil, fmt.Errorf("%w: truncated fmt chunk", ErrNotWAV)
+			}
+			f, err := parseFmtChunk(body[:size])
+			if err != nil {
+				return nil, err
+			}
+			format = &f
+
+		case "data":
+			if format == nil {
+				return nil, fmt.Errorf("%w: data chunk before fmt chunk", ErrNotWAV)
+			}
+			data := r
+			if size != streamedSize && size != 0 {
+				data = io.LimitReader(r, int64(size))
+			}
+			return &WAV{Format: *format, Data: data}, nil
+
+		default:
+			// RIFF chunks are padded to an even length
+			if _, err := io.CopyN(io.Discard, r, int64(size)+int64(size%2)); err != nil {
+				return nil, fmt.Errorf("%w: truncated %q chunk", ErrNotWAV, id)
+			}
+		}
+	}
+}
+
+func parseFmtChunk(body []byte) (Format, error) {
+	tag := binary.LittleEndian.Uint16(body[0:2])
+	f := Format{
+		Channels:      int(binary.LittleEndian.Uint16(body[2:4])),
+		SampleRate:    int(binary.LittleEndian.Uint32(body[4:8])),
+		BitsPerSample: int(binary.LittleEndian.Uint16(body[14:16])),
+	}
+	blockAlign := int(binary.LittleEndian.Uint16(body[12:14]))
+
+	// WAVE_FORMAT_EXTENSIBLE keeps the real tag at the start of the
+	// sub-format GUID
+	if tag == wavFormatExtensible {
+		if len(body) < 40 {
+			return Format{}, fmt.Errorf("%w: truncated extensible fmt chunk", ErrNotWAV)
+		}
+		tag = binary.LittleEndian.Uint16(body[24:26])
+	}
+
+	switch tag {
+	case wavFormatPCM:
+		f.Encoding = EncodingPCM
+	case wavFormatFloat:
+		f.Encoding = EncodingFloat
+	default:
+		return Format{}, fmt.Errorf("%w: format tag 0x%04x (compressed audio)", ErrUnsupportedFormat, tag)
+	}
+
+	if err := f.validate(); err != nil {
+		return Format{}, err
+	}
+	if blockAlign != f.FrameSize() {
+		return Format{}, fmt.Errorf("%w: block align %d does not match %s", ErrNotWAV, blockAlign, f)
+	}
+	return f, nil
+}
@@ -0,0 +1,178 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+// chunk encodes a RIFF chunk, padding odd-sized bodies as the format requires.
+func chunk(id string, body []byte) []byte {
+	b := []byte(id)
+	b = binary.LittleEndian.AppendUint32(b, uint32(len(body)))
+	b = append(b, body...)
+	if len(body)%2 == 1 {
+		b = append(b, 0)
+	}
+	return b
+}
+
+func fmtBody(tag, channels, sampleRate, bits int) []byte {
+	blockAlign := channels * bits / 8
+	var b []byte
+	b = binary.LittleEndian.AppendUint16(b, uint16(tag))
+	b = binary.LittleEndian.AppendUint16(b, uint16(channels))
+	b = binary.LittleEndian.AppendUint32(b, uint32(sampleRate))
+	b = binary.LittleEndian.AppendUint32(b, uint32(sampleRate*blockAlign))
+	b = binary.LittleEndian.AppendUint16(b, uint16(blockAlign))
+	b = binary.LittleEndian.AppendUint16(b, uint16(bits))
+	return b
+}
+
+func riff(chunks ...[]byte) []byte {
+	body := []byte("WAVE")
+	for _, c := range chunks {
+		body = append(body, c...)
+	}
+	return chunk("RIFF", body)
+}
+
+func TestDecodeWAV(t *testing.T) {
+	samples := []byte{1, 2, 3, 4, 5, 6, 7, 8}
+	mono16 := Format{SampleRate: 22050, Channels: 1, BitsPerSample: 16, Encoding: EncodingPCM}
+
+	tests := []struct {
+		name   string
+		file   []byte
+		format Format
+	}{
+		{
+			name:   "plain",
+			file:   riff(chunk("fmt ", fmtBody(wavFormatPCM, 1, 22050, 16)), chunk("data", samples)),
+			format: mono16,
+		},
+		{
+			name: "extra chunks",
+			file: riff(
+				chunk("LIST", []byte("INFOISFT\x0e\x00\x00\x00Lavf60.16.100")),
+				chunk("fmt ", fmtBody(wavFormatPCM, 1, 22050, 16)),
+				chunk("fact", []byte{4, 0, 0, 0}),
+				chunk("data", samples),
+			),
+			format: mono16,
+		},
+		{
+			name: "odd chunk padding",
+			file: riff(
+				chunk("fmt ", fmtBody(wavFormatPCM, 1, 22050, 16)),
+				chunk("junk", []byte{1, 2, 3}),
+				chunk("data", samples),
+			),
+			format: mono16,
+		},
+		{
+			name:   "8-bit",
+			file:   riff(chunk("fmt ", fmtBody(wavFormatPCM, 1, 8000, 8)), chunk("data", samples)),
+			format: Format{SampleRate: 8000, Channels: 1, BitsPerSample: 8, Encoding: EncodingPCM},
+		},
+		{
+			name:   "24-bit stereo",
+			file:   riff(chunk("fmt ", fmtBody(wavFormatPCM, 2, 48000, 24)), chunk("data", samples[:6])),
+			format: Format{SampleRate: 48000, Channels: 2, BitsPerSample: 24, Encoding: EncodingPCM},
+		},
+		{
+			name:   "float",
+			file:   riff(chunk("fmt ", fmtBody(wavFormatFloat, 1, 24000, 32)), chunk("data", samples)),
+			format: Format{SampleRate: 24000, Channels: 1, BitsPerSample: 32, Encoding: EncodingFloat},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wav, err := DecodeWAV(bytes.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("DecodeWAV: %v", err)
+			}
+			if wav.Format != tt.format {
+				t.Errorf("format = %v, want %v", wav.Format, tt.format)
+			}
+			data, err := io.ReadAll(wav.Data)
+			if err != nil {
+				t.Fatalf("reading data: %v", err)
+			}
+			if !bytes.HasPrefix(samples, data) || len(data) == 0 {
+				t.Errorf("data = %v, want a prefix of %v", data, samples)
+			}
+		})
+	}
+}
+
+func TestDecodeWAVStreamed(t *testing.T) {
+	// Tools writing to a pipe leave the data size at 0xFFFFFFFF
+	file := riff(chunk("fmt ", fmtBody(wavFormatPCM, 1, 22050, 16)))
+	file = append(file, "data\xff\xff\xff\xff"...)
+	file = append(file, make([]byte, 1000)...)
+
+	wav, err := DecodeWAV(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("DecodeWAV: %v", err)
+	}
+	data, _ := io.ReadAll(wav.Data)
+	if len(data) != 1000 {
+		t.Errorf("read %d bytes of data, want 1000", len(data))
+	}
+}
+
+func TestDecodeWAVTruncatedData(t *testing.T) {
+	// The data chunk claims 100 bytes but the file ends after 40
+	file := riff(chunk("fmt ", fmtBody(wavFormatPCM, 1, 22050, 16)))
+	file = append(file, "data\x64\x00\x00\x00"...)
+	file = append(file, make([]byte, 40)...)
+
+	wav, err := DecodeWAV(bytes.NewReader(file))
+	if err != nil {
+		t.Fatalf("DecodeWAV: %v", err)
+	}
+	data, err := io.ReadAll(wav.Data)
+	if err != nil || len(data) != 40 {
+		t.Errorf("read %d bytes, %v; want the 40 bytes present", len(data), err)
+	}
+}
+
+func TestDecodeWAVErrors(t *testing.T) {
+	pcm := chunk("fmt ", fmtBody(wavFormatPCM, 1, 22050, 16))
+	data := chunk("data", []byte{0, 0})
+
+	badAlign := fmtBody(wavFormatPCM, 2, 22050, 16)
+	binary.LittleEndian.PutUint16(badAlign[12:14], 2)
+
+	tests := []struct {
+		name string
+		file []byte
+		want error
+	}{
+		{"empty", nil, ErrNotWAV},
+		{"not RIFF", []byte("ID3\x04\x00\x00\x00\x00\x00\x00\x00\x00"), ErrNotWAV},
+		{"RIFF but not WAVE", chunk("RIFF", []byte("AVI LIST")), ErrNotWAV},
+		{"compressed", riff(chunk("fmt ", fmtBody(0x0055, 1, 22050, 16)), data), ErrUnsupportedFormat},
+		{"12-bit PCM", riff(chunk("fmt ", fmtBody(wavFormatPCM, 1, 22050, 12)), data), ErrUnsupportedFormat},
+		{"16-bit float", riff(chunk("fmt ", fmtBody(wavFormatFloat, 1, 22050, 16)), data), ErrUnsupportedFormat},
+		{"no channels", riff(chunk("fmt ", fmtBody(wavFormatPCM, 0, 22050, 16)), data), ErrUnsupportedFormat},
+		{"sample rate too low", riff(chunk("fmt ", fmtBody(wavFormatPCM, 1, 100, 16)), data), ErrUnsupportedFormat},
+		{"block align mismatch", riff(chunk("fmt ", badAlign), data), ErrNotWAV},
+		{"data before fmt", riff(data, pcm), ErrNotWAV},
+		{"no fmt", riff(chunk("LIST", []byte("INFO"))), ErrNotWAV},
+		{"no data", riff(pcm), ErrNoData},
+		{"truncated fmt", riff(pcm)[:30], ErrNotWAV},
+		{"truncated chunk", riff(pcm, chunk("LIST", make([]byte, 64)))[:60], ErrNotWAV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DecodeWAV(bytes.NewReader(tt.file))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrAdmissionDenied  = errors.New("request to join was denied")
+	ErrAdmissionTimeout = errors.New("timed out waiting to be admitted")
+	ErrWrongPasscode    = errors.New("meeting passcode was rejected")
+	ErrMeetingEnded     = errors.New("meeting has ended")
+)
+
+const (
+	defaultAdmissionTimeout = 5 * time.Minute
+	admissionPollInterval   = time.Second
+	withdrawTimeout         = 15 * time.Second
+)
+
+// waitForAdmission polls the platform while the bot sits in a lobby or
+// waiting room until the host lets it in, turns it away, or timeout passes.
+// On timeout or cancellation the request to join is withdrawn so the host is
+// not asked to admit a bot that has given up. pageLock, if not nil, is held
+// by the caller and released between polls.
+func (b *Bot) waitForAdmission(ctx context.Context, timeout time.Duration, pageLock sync.Locker) error {
+	platform := b.currentPlatform()
+	fmt.Printf("Waiting up to %v for the host to admit us...\n", timeout)
+
+	deadline := time.Now().Add(timeout)
+	for {
+		// Someone else may have used the page while we were not holding it
+		switch b.State().State {
+		case StateWaitingInLobby:
+		case StateInMeeting:
+			return nil
+		default:
+			return fmt.Errorf("left the lobby while waiting to be admitted (state %s)", b.State().State)
+		}
+
+		status, err := platform.DetectState(ctx)
+		if err != nil {
+			log.Printf("[ADMISSION] Failed to read meeting state: %v", err)
+		} else if status.Rejected != "" {
+			return rejectionError(platform, status.Rejected)
+		} else if status.InMeeting {
+			fmt.Println("Admitted to the meeting!")
+			return b.admitted(ctx)
+		}
+
+		if time.Now().After(deadline) {
+			log.Printf("[ADMISSION] Not admitted after %v, withdrawing request", timeout)
+			b.withdrawJoinRequest()
+			return fmt.Errorf("%w after %v", ErrAdmissionTimeout, timeout)
+		}
+
+		if pageLock != nil {
+			pageLock.Unlock()
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(admissionPollInterval):
+		}
+		if pageLock != nil {
+			pageLock.Lock()
+		}
+
+		// The session may have been closed while the lock was released
+		if !b.running || b.page == nil {
+			return ErrNotRunning
+		}
+
+		if ctx.Err() != nil {
+			log.Printf("[ADMISSION] Join cancelled, withdrawing request")
+			if b.inState(StateWaitingInLobby) {
+				b.withdrawJoinRequest()
+			}
+			return ctx.Err()
+		}
+	}
+}
+
+// rejectionError wraps the reason a platform turned the bot away. Unless
+// the platform recognises it as something else, such as a wrong passcode,
+// it is the host denying the request.
+func rejectionError(platform MeetingPlatform, rejected string) error {
+	if c, ok := platform.(rejectionClassifier); ok {
+		if err := c.classifyRejection(rejected); err != nil {
+			return fmt.Errorf("%w: %s", err, rejected)
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrAdmissionDenied, rejected)
+}
+
+// withdrawJoinRequest leaves the lobby. Navigating away afterwards makes sure
+// the request is dropped even if the platform's leave button was not found.
+// It runs on its own short deadline since the join's context may already be
+// cancelled.
+func (b *Bot) withdrawJoinRequest() {
+	ctx, cancel := context.WithTimeout(context.Background(), withdrawTimeout)
+	defer cancel()
+
+	if err := b.currentPlatform().Leave(ctx); err != nil {
+		log.Printf("[ADMISSION] Leave failed, navigating away instead: %v", err)
+	}
+	b.page.Goto("about:blank")
+}
+
+// visibleText returns the trimmed text of the first visible element matching
+// any of the selectors, or "" if none is visible.
+func (b *Bot) visibleText(selectors []string) string {
+	for _, selector := range selectors {
+		locator := b.page.Locator(selector).First()
+		visible, err := locator.IsVisible()
+		if err != nil || !visible {
+			continue
+		}
+		text, err := locator.TextContent()
+		if err != nil || strings.TrimSpace(text) == "" {
+			return strings.TrimPrefix(selector, "text=")
+		}
+		return strings.TrimSpace(text)
+	}
+	return ""
+}
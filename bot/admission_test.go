@@ -0,0 +1,78 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// lobbyPlatform reports whatever status it is given, standing in for a page
+// showing a lobby.
+type lobbyPlatform struct {
+	MeetingPlatform
+	mu     sync.Mutex
+	status MeetingStatus
+}
+
+func (p *lobbyPlatform) Name() string { return "lobby" }
+
+func (p *lobbyPlatform) DetectState(ctx context.Context) (*MeetingStatus, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	status := p.status
+	return &status, nil
+}
+
+// livePage lets a bot look like it has a browser without starting one.
+type livePage struct{ playwright.Page }
+
+func lobbyBot(platform MeetingPlatform) *Bot {
+	return &Bot{
+		page:       livePage{},
+		running:    true,
+		platform:   platform,
+		state:      StateWaitingInLobby,
+		stateSince: time.Now(),
+	}
+}
+
+func TestWaitForAdmissionDenied(t *testing.T) {
+	b := lobbyBot(&lobbyPlatform{status: MeetingStatus{Rejected: "Someone in the call denied your request"}})
+
+	err := b.waitForAdmission(context.Background(), time.Minute, nil)
+	if !errors.Is(err, ErrAdmissionDenied) {
+		t.Fatalf("err = %v, want ErrAdmissionDenied", err)
+	}
+}
+
+func TestWaitForAdmissionBotClosed(t *testing.T) {
+	b := lobbyBot(&lobbyPlatform{})
+	var pageLock sync.Mutex
+	pageLock.Lock()
+
+	// Close the bot the way a session teardown does, under the page lock,
+	// while the join is between polls
+	go func() {
+		pageLock.Lock()
+		b.page = nil
+		b.running = false
+		b.setState(StateIdle)
+		pageLock.Unlock()
+	}()
+
+	done := make(chan error)
+	go func() { done <- b.waitForAdmission(context.Background(), time.Minute, &pageLock) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrNotRunning) {
+			t.Fatalf("err = %v, want ErrNotRunning", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("waitForAdmission did not notice the bot was closed")
+	}
+	pageLock.Unlock()
+}
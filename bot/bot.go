@@ -2,35 +2,69 @@ package bot
 
 import (
 	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/playwright-community/playwright-go"
 )
 
+// ErrNotRunning is returned by operations on a bot whose browser has not
+// been started or has been closed.
+var ErrNotRunning = errors.New("bot not initialized")
+
 type Bot struct {
 	// Playwright instances
 	pw      *playwright.Playwright
 	browser playwright.Browser
+	context playwright.BrowserContext
 	page    playwright.Page
 
 	// Configuration
-	headless bool
-	email    string
-	password string
+	headless         bool
+	guest            bool // join Google Meet signed out, without logging in
+	email            string
+	password         string
+	totpKey          []byte        // decoded GOOGLE_TOTP_SECRET, nil if 2-Step Verification is not automated
+	displayName      string        // name shown on platforms that join as a guest
+	audioSource      string        // PulseAudio source for the browser, empty for the default
+	admissionTimeout time.Duration // default lobby wait, see JoinOptions
+	storagePath      string        // encrypted cookies/localStorage, see storage.go
+	storageKey       []byte        // nil disables storage state persistence
+
+	// Activity events
+	events      *EventBus
+	eventSource string
+
+	// Platform of the current or last meeting
+	platform MeetingPlatform
 
 	// State
-	running bool
+	running       bool
+	googleSession atomic.Bool // signed in to Google, so IsLoggedIn can be skipped
+	storageMu     sync.Mutex  // guards context for storage state access outside the page lock
+	stateMu       sync.Mutex
+	state         State
+	stateSince    time.Time
+	transitions   []Transition
+	lastErr       error
+	lastErrAt     time.Time
 }
 
-func (b *Bot) findElementFast(selectors []string, timeout int) (string, error) {
+func (b *Bot) findElementFast(ctx context.Context, selectors []string, timeout int) (string, error) {
 	for _, selector := range selectors {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
 			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(float64(timeout)),
+			Timeout: pwTimeout(ctx, float64(timeout)),
 		})
 		if err == nil {
 			return selector, nil
@@ -39,7 +73,7 @@ func (b *Bot) findElementFast(selectors []string, timeout int) (string, error) {
 	return "", fmt.Errorf("none of the selectors found an element within %dms", timeout)
 }
 
-func (b *Bot) ClearPopups() {
+func (b *Bot) ClearPopups(ctx context.Context) {
 	log.Printf("[POPUP_CLEARING] Starting popup clearing process...")
 
 	// Common popup selectors that can appear in Google Meet
@@ -88,6 +122,10 @@ func (b *Bot) ClearPopups() {
 
 	// Try to dismiss each type of popup
 	for _, selector := range popupSelectors {
+		if ctx.Err() != nil {
+			log.Printf("[POPUP_CLEARING] Cancelled")
+			return
+		}
 		elements, err := b.page.Locator(selector).All()
 		if err != nil {
 			continue
@@ -101,7 +139,7 @@ func (b *Bot) ClearPopups() {
 
 			log.Printf("[POPUP_CLEARING] Dismissing popup with selector: %s", selector)
 			err = element.Click(playwright.LocatorClickOptions{
-				Timeout: playwright.Float(1000),
+				Timeout: pwTimeout(ctx, 1000),
 			})
 			if err != nil {
 				log.Printf("[POPUP_CLEARING] Failed to click popup: %v", err)
@@ -109,26 +147,40 @@ func (b *Bot) ClearPopups() {
 			}
 
 			// Wait for popup to disappear
-			time.Sleep(500 * time.Millisecond)
+			sleep(ctx, 500*time.Millisecond)
 			log.Printf("[POPUP_CLEARING] Successfully dismissed popup")
+			b.emit(EventPopupDismissed, map[string]any{"selector": selector})
 		}
 	}
 
 	// Wait a moment for any remaining popups to settle
-	time.Sleep(1000 * time.Millisecond)
+	sleep(ctx, 1000*time.Millisecond)
 	log.Printf("[POPUP_CLEARING] Popup clearing completed")
 }
 
-func (b *Bot) clickWithLogging(selector, action, context string) error {
-	b.logButtonClick(action, selector, context)
+func (b *Bot) clickWithLogging(ctx context.Context, selector, action, clickContext string) error {
+	b.logButtonClick(action, selector, clickContext)
 
-	err := b.page.Locator(selector).Click()
+	err := b.page.Locator(selector).Click(playwright.LocatorClickOptions{
+		Timeout: pwTimeout(ctx, clickTimeout),
+	})
 	if err != nil {
 		log.Printf("[BUTTON_CLICK_ERROR] Failed to click %s: %v", selector, err)
+		b.emit(EventButtonClick, map[string]any{
+			"action":   action,
+			"selector": selector,
+			"context":  clickContext,
+			"error":    err.Error(),
+		})
 		return err
 	}
 
 	log.Printf("[BUTTON_CLICK_SUCCESS] Successfully clicked: %s", selector)
+	b.emit(EventButtonClick, map[string]any{
+		"action":   action,
+		"selector": selector,
+		"context":  clickContext,
+	})
 	return nil
 }
 
@@ -138,13 +190,58 @@ func (b *Bot) logButtonClick(action, selector, context string) {
 		timestamp, action, selector, context)
 }
 
-func (b *Bot) GoogleLogin() error {
+// requireGoogleAccount refuses Google Meet work when neither an account nor
+// guest mode is configured.
+func (b *Bot) requireGoogleAccount() error {
+	if b.email == "" && !b.guest {
+		return fmt.Errorf("%w: set GOOGLE_EMAIL and GOOGLE_PASSWORD, or BOT_GUEST_MODE=true to join Google Meet as a guest", ErrNoGoogleAccount)
+	}
+	return nil
+}
+
+// GoogleLogin signs in with the configured account. On failure the returned
+// error wraps one of the Err* login sentinels where the cause is known, and
+// the LoginResult says what Google showed.
+func (b *Bot) GoogleLogin(ctx context.Context) (*LoginResult, error) {
 	if !b.running {
-		return fmt.Errorf("bot not initialized")
+		return nil, ErrNotRunning
+	}
+	if b.guest {
+		return nil, fmt.Errorf("bot is in guest mode, no Google account configured")
+	}
+	if err := b.requireGoogleAccount(); err != nil {
+		return nil, err
 	}
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := b.setState(StateLoggingIn); err != nil {
+		return nil, err
+	}
+	err := b.googleLogin(ctx)
+	result := newLoginResult(err, b.page.URL())
+	if err != nil {
+		log.Printf("[GOOGLE_LOGIN] Login failed (%s): %v", result.Outcome, err)
+		b.emit(EventLoginFailed, map[string]any{
+			"error":     err.Error(),
+			"outcome":   result.Outcome,
+			"retryable": result.Retryable,
+		})
+		return result, b.fail(err)
+	}
+	b.googleSession.Store(true)
+	if err := b.saveStorageState(); err != nil {
+		log.Printf("[STORAGE_STATE] Failed to save after login: %v", err)
+	}
+	b.emit(EventLoginSucceeded, nil)
+	return result, b.setState(StateLoggedIn)
+}
+
+func (b *Bot) googleLogin(ctx context.Context) error {
 	_, err := b.page.Goto("https://accounts.google.com/signin/v2/identifier?flowName=GlifWebSignIn&flowEntry=ServiceLogin", playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateNetworkidle,
+		Timeout:   pwTimeout(ctx, navigationTimeout),
 	})
 
 	if err != nil {
@@ -163,7 +260,7 @@ func (b *Bot) GoogleLogin() error {
 	for _, selector := range emailSelectors {
 		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
 			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(1000), // Reduced from 5000ms to 1000ms
+			Timeout: pwTimeout(ctx, 1000), // Reduced from 5000ms to 1000ms
 		})
 		if err == nil {
 			emailInput = selector
@@ -184,6 +281,10 @@ func (b *Bot) GoogleLogin() error {
 		return fmt.Errorf("failed to type email in: %v", err)
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	// Click the "Next" button after email input
 	emailNextSelectors := []string{
 		"div#identifierNext",
@@ -197,7 +298,7 @@ func (b *Bot) GoogleLogin() error {
 	for _, selector := range emailNextSelectors {
 		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
 			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(1000), // Reduced from 3000ms to 1000ms
+			Timeout: pwTimeout(ctx, 1000), // Reduced from 3000ms to 1000ms
 		})
 		if err == nil {
 			emailNextButton = selector
@@ -210,7 +311,7 @@ func (b *Bot) GoogleLogin() error {
 		return fmt.Errorf("failed to find email next button")
 	}
 
-	err = b.clickWithLogging(emailNextButton, "CLICK_EMAIL_NEXT", "Google Login - Email Step")
+	err = b.clickWithLogging(ctx, emailNextButton, "CLICK_EMAIL_NEXT", "Google Login - Email Step")
 	if err != nil {
 		return fmt.Errorf("failed to click email next button: %v", err)
 	}
@@ -220,7 +321,8 @@ func (b *Bot) GoogleLogin() error {
 
 	// Add a small delay to ensure page transition
 	err = b.page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
-		State: playwright.LoadStateNetworkidle,
+		State:   playwright.LoadStateNetworkidle,
+		Timeout: pwTimeout(ctx, navigationTimeout),
 	})
 	if err != nil {
 		fmt.Printf("Warning: Failed to wait for network idle: %v\n", err)
@@ -242,7 +344,7 @@ func (b *Bot) GoogleLogin() error {
 		fmt.Printf("Trying password selector %d: %s\n", i+1, selector)
 		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
 			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(800), // Reduced from 3000ms to 800ms
+			Timeout: pwTimeout(ctx, 800), // Reduced from 3000ms to 800ms
 		})
 		if err == nil {
 			passwordInput = selector
@@ -268,7 +370,7 @@ func (b *Bot) GoogleLogin() error {
 		labelLocator := b.page.GetByLabel("Enter your password")
 		err := labelLocator.WaitFor(playwright.LocatorWaitForOptions{
 			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(1000), // Reduced from 3000ms to 1000ms
+			Timeout: pwTimeout(ctx, 1000), // Reduced from 3000ms to 1000ms
 		})
 		if err == nil {
 			fmt.Println("✓ Found password field using getByLabel")
@@ -279,6 +381,9 @@ func (b *Bot) GoogleLogin() error {
 				return fmt.Errorf("failed to type password using getByLabel: %v", err)
 			}
 		} else {
+			if err := b.loginFailure(loginStepEmail); err != nil {
+				return err
+			}
 			return fmt.Errorf("failed to find password input field with any method")
 		}
 	} else {
@@ -290,6 +395,10 @@ func (b *Bot) GoogleLogin() error {
 		}
 	}
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	// Try multiple selectors for the next button
 	nextButtonSelectors := []string{
 		"div#passwordNext",
@@ -303,7 +412,7 @@ func (b *Bot) GoogleLogin() error {
 	for _, selector := range nextButtonSelectors {
 		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
 			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(2000),
+			Timeout: pwTimeout(ctx, 2000),
 		})
 		if err == nil {
 			nextButton = selector
@@ -313,7 +422,7 @@ func (b *Bot) GoogleLogin() error {
 	}
 
 	if nextButton != "" {
-		err = b.clickWithLogging(nextButton, "CLICK_PASSWORD_NEXT", "Google Login - Password Step")
+		err = b.clickWithLogging(ctx, nextButton, "CLICK_PASSWORD_NEXT", "Google Login - Password Step")
 		if err != nil {
 			return fmt.Errorf("failed to click next button: %v", err)
 		}
@@ -327,6 +436,10 @@ func (b *Bot) GoogleLogin() error {
 		}
 	}
 
+	if err := b.completeTwoStep(ctx); err != nil {
+		return err
+	}
+
 	fmt.Println("Completing login...")
 
 	// Wait for navigation or success indicators instead of timeout
@@ -335,7 +448,7 @@ func (b *Bot) GoogleLogin() error {
 
 	// First, try waiting for URL changes that indicate successful login
 	err = b.page.WaitForURL("**/myaccount.google.com/**", playwright.PageWaitForURLOptions{
-		Timeout: playwright.Float(3000), // Reduced from 10000ms to 3000ms
+		Timeout: pwTimeout(ctx, 3000), // Reduced from 10000ms to 3000ms
 	})
 	if err == nil {
 		fmt.Println("Google login successful - redirected to myaccount")
@@ -344,7 +457,7 @@ func (b *Bot) GoogleLogin() error {
 
 	if !loginSuccessful {
 		err = b.page.WaitForURL("**/accounts.google.com/signin/oauth/**", playwright.PageWaitForURLOptions{
-			Timeout: playwright.Float(2000), // Reduced from 5000ms to 2000ms
+			Timeout: pwTimeout(ctx, 2000), // Reduced from 5000ms to 2000ms
 		})
 		if err == nil {
 			fmt.Println("Google login successful - OAuth redirect")
@@ -362,7 +475,7 @@ func (b *Bot) GoogleLogin() error {
 		for _, selector := range successSelectors {
 			err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
 				State:   playwright.WaitForSelectorStateVisible,
-				Timeout: playwright.Float(5000),
+				Timeout: pwTimeout(ctx, 5000),
 			})
 			if err == nil {
 				fmt.Println("Google login successful - found success indicator")
@@ -377,229 +490,66 @@ func (b *Bot) GoogleLogin() error {
 		currentUrl := b.page.URL()
 		fmt.Printf("Current URL after login attempt: %s\n", currentUrl)
 
-		// Check if URL indicates successful login
-		if currentUrl != "" &&
-			(strings.Contains(currentUrl, "myaccount.google.com") ||
-				strings.Contains(currentUrl, "accounts.google.com/signin/oauth") ||
-				!strings.Contains(currentUrl, "signin/v2/identifier")) {
-			fmt.Println("Google login appears successful based on URL")
-		} else {
-			// Check if there's an error message
-			errorLocator := b.page.Locator("[jsname='B34EJ'] span")
-			err := errorLocator.WaitFor(playwright.LocatorWaitForOptions{
-				State:   playwright.WaitForSelectorStateVisible,
-				Timeout: playwright.Float(2000),
-			})
-			if err == nil {
-				errorText, _ := errorLocator.TextContent()
-				fmt.Printf("Login error: %s\n", errorText)
-				return fmt.Errorf("google login failed: %s", errorText)
-			} else {
-				fmt.Println("Login status unclear, continuing...")
-			}
+		if err := b.loginFailure(loginStepPassword); err != nil {
+			fmt.Printf("Login error: %v\n", err)
+			return err
+		}
+
+		// Google only lets the browser off the sign-in pages once the
+		// login is complete
+		if !signedIn(currentUrl) {
+			return fmt.Errorf("%w: still on %s", ErrLoginUnconfirmed, currentUrl)
 		}
+		fmt.Println("Google login successful - left the sign-in pages")
 	}
 
 	return nil
 }
 
+// Close shuts down the browser and the Playwright driver. It always attempts
+// both steps so a failing browser close does not orphan the driver process,
+// and for the same reason takes no context.
 func (b *Bot) Close() error {
+	var firstErr error
+
+	// Keep any cookies Google refreshed while we were running
+	if b.googleSession.Load() {
+		if err := b.saveStorageState(); err != nil {
+			log.Printf("[STORAGE_STATE] Failed to save before closing: %v", err)
+		}
+	}
+
 	if b.browser != nil {
+		log.Printf("[BROWSER_CLOSE] Closing browser...")
 		if err := b.browser.Close(); err != nil {
-			return err
+			log.Printf("[BROWSER_CLOSE] Failed to close browser: %v", err)
+			firstErr = fmt.Errorf("failed to close browser: %v", err)
 		}
+		b.browser = nil
 	}
 	if b.pw != nil {
+		log.Printf("[BROWSER_CLOSE] Stopping playwright...")
 		if err := b.pw.Stop(); err != nil {
-			return err
-		}
-	}
-	b.running = false
-	return nil
-}
-
-// JoinGoogleMeet joins a Google Meet meeting using the provided URL
-func (b *Bot) JoinGoogleMeet(meetingURL string) error {
-	if !b.running {
-		return fmt.Errorf("bot not initialized")
-	}
-
-	fmt.Printf("Joining Google Meet: %s\n", meetingURL)
-
-	// Navigate to the meeting URL
-	_, err := b.page.Goto(meetingURL, playwright.PageGotoOptions{
-		WaitUntil: playwright.WaitUntilStateNetworkidle,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to navigate to meeting URL: %v", err)
-	}
-
-	// Wait for the page to load
-	err = b.page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
-		State: playwright.LoadStateNetworkidle,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to wait for page load: %v", err)
-	}
-
-	// Check if we need to login first
-	currentUrl := b.page.URL()
-	if strings.Contains(currentUrl, "accounts.google.com") && strings.Contains(currentUrl, "signin") {
-		fmt.Println("Need to login first...")
-		err = b.GoogleLogin()
-		if err != nil {
-			return fmt.Errorf("failed to login before joining meeting: %v", err)
-		}
-
-		// Navigate back to meeting after login
-		_, err = b.page.Goto(meetingURL, playwright.PageGotoOptions{
-			WaitUntil: playwright.WaitUntilStateNetworkidle,
-		})
-		if err != nil {
-			return fmt.Errorf("failed to navigate back to meeting after login: %v", err)
-		}
-	}
-
-	// Clear any popups that might interfere
-	b.ClearPopups()
-
-	// Handle microphone and camera permissions
-	fmt.Println("Handling microphone and camera settings...")
-
-	// Try to turn off camera
-	cameraSelectors := []string{
-		"div[data-tooltip*='camera']",
-		"button[aria-label*='camera']",
-		"div[aria-label*='Turn off camera']",
-		"button[data-tooltip*='Turn off camera']",
-	}
-
-	cameraSelector, err := b.findElementFast(cameraSelectors, 1000)
-	if err == nil {
-		fmt.Printf("Found camera button with selector: %s\n", cameraSelector)
-		b.clickWithLogging(cameraSelector, "TOGGLE_CAMERA_OFF", "Google Meet - Pre-join Setup")
-	}
-
-	// Try to turn off microphone initially (we'll control it via virtual mic)
-	micSelectors := []string{
-		"div[data-tooltip*='microphone']",
-		"button[aria-label*='microphone']",
-		"div[aria-label*='Turn off microphone']",
-		"button[data-tooltip*='Turn off microphone']",
-	}
-
-	micSelector, err := b.findElementFast(micSelectors, 1000)
-	if err == nil {
-		fmt.Printf("Found microphone button with selector: %s\n", micSelector)
-		b.clickWithLogging(micSelector, "TOGGLE_MIC_OFF", "Google Meet - Pre-join Setup")
-	}
-
-	// Clear popups again after handling camera/microphone
-	b.ClearPopups()
-
-	// Look for and click the "Join now" button with retry logic
-	fmt.Println("Looking for join button...")
-	joinSelectors := []string{
-		"button:has-text('Join now')",
-		"div[role='button']:has-text('Join now')",
-		"button:has-text('Ask to join')",
-		"div[role='button']:has-text('Ask to join')",
-		"button[aria-label*='Join']",
-		"div[data-tooltip*='Join']",
-	}
-
-	joinButtonFound := false
-	maxRetries := 2
-
-	for retry := 0; retry < maxRetries && !joinButtonFound; retry++ {
-		if retry > 0 {
-			fmt.Printf("Retry attempt %d for join button...\n", retry)
-			b.ClearPopups() // Clear popups before retry
-		}
-
-		for _, selector := range joinSelectors {
-			err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
-				State:   playwright.WaitForSelectorStateVisible,
-				Timeout: playwright.Float(1500),
-			})
-			if err == nil {
-				fmt.Printf("Found join button with selector: %s\n", selector)
-				err = b.clickWithLogging(selector, "JOIN_MEETING", "Google Meet - Join Meeting")
-				if err != nil {
-					log.Printf("[JOIN_BUTTON_ERROR] Failed to click join button on attempt %d: %v", retry+1, err)
-					if retry < maxRetries-1 {
-						continue // Try next selector or retry
-					}
-					return fmt.Errorf("failed to click join button after %d attempts: %v", maxRetries, err)
-				}
-				joinButtonFound = true
-				break
+			log.Printf("[BROWSER_CLOSE] Failed to stop playwright: %v", err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("failed to stop playwright: %v", err)
 			}
 		}
+		b.pw = nil
 	}
-
-	if !joinButtonFound {
-		return fmt.Errorf("could not find join button after %d attempts", maxRetries)
-	}
-
-	// Wait for meeting to load
-	fmt.Println("Waiting for meeting to load...")
-
-	// Wait for meeting interface elements
-	meetingSelectors := []string{
-		"div[data-allocation-index]", // Meeting participants area
-		"div[jsname='HzV7m']",        // Meeting controls
-		"button[aria-label*='Leave call']",
-		"div[aria-label*='You joined']",
-	}
-
-	meetingJoined := false
-	for _, selector := range meetingSelectors {
-		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
-			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(3000), // Reduced from 15000ms to 3000ms
-		})
-		if err == nil {
-			fmt.Println("Successfully joined the meeting!")
-			meetingJoined = true
-			break
-		}
-	}
-
-	if !meetingJoined {
-		fmt.Println("Meeting join status unclear, but continuing...")
-	}
-
-	return nil
-}
-
-func (b *Bot) EnableMicrophone() error {
-	if !b.running {
-		return fmt.Errorf("bot not initialized")
-	}
-
-	micSelectors := []string{
-		"button[aria-label*='Turn on microphone']",
-		"div[data-tooltip*='Turn on microphone']",
-		"button[aria-label*='Unmute']",
-		"div[aria-label*='Unmute']",
-	}
-
-	for _, selector := range micSelectors {
-		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
-			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(2000),
-		})
-		if err == nil {
-			fmt.Printf("Enabling microphone with selector: %s\n", selector)
-			return b.clickWithLogging(selector, "ENABLE_MICROPHONE", "Google Meet - Meeting Controls")
-		}
-	}
-
-	return fmt.Errorf("could not find microphone enable button")
+	b.storageMu.Lock()
+	b.context = nil
+	b.storageMu.Unlock()
+	b.page = nil
+	b.running = false
+	b.googleSession.Store(false)
+	b.setState(StateIdle)
+	return firstErr
 }
 
-func loadEnv() error {
+// LoadEnv loads KEY=value pairs from the .env file in the working directory
+// into the process environment.
+func LoadEnv() error {
 	file, err := os.Open(".env")
 	if err != nil {
 		return fmt.Errorf("failed to open .env file: %v", err)
@@ -634,29 +584,103 @@ func loadEnv() error {
 
 func NewBot(headless bool) (*Bot, error) {
 	// Load environment variables from .env file
-	if err := loadEnv(); err != nil {
+	if err := LoadEnv(); err != nil {
 		return nil, fmt.Errorf("failed to load .env file: %v", err)
 	}
 
 	email := os.Getenv("GOOGLE_EMAIL")
 	password := os.Getenv("GOOGLE_PASSWORD")
 
-	if email == "" {
-		return nil, fmt.Errorf("GOOGLE_EMAIL not found in .env file")
+	// Guest mode asks to join Meet calls signed out. It has to be asked for:
+	// a missing GOOGLE_EMAIL only leaves Meet unavailable, so a config
+	// mistake can't turn signed-in joins into guest ones.
+	guest := os.Getenv("BOT_GUEST_MODE") == "true"
+	if email == "" && !guest {
+		log.Printf("[CONFIG] GOOGLE_EMAIL not set and BOT_GUEST_MODE is not true, Google Meet joins will be refused")
 	}
-	if password == "" {
+	if !guest && email != "" && password == "" {
 		return nil, fmt.Errorf("GOOGLE_PASSWORD not found in .env file")
 	}
 
+	// Optional base32 secret of the account's authenticator app
+	var totpKey []byte
+	if secret := os.Getenv("GOOGLE_TOTP_SECRET"); secret != "" {
+		key, err := decodeTOTPSecret(secret)
+		if err != nil {
+			return nil, fmt.Errorf("GOOGLE_TOTP_SECRET: %v", err)
+		}
+		totpKey = key
+	}
+
+	admissionTimeout := defaultAdmissionTimeout
+	if v := os.Getenv("ADMISSION_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("invalid ADMISSION_TIMEOUT %q", v)
+		}
+		admissionTimeout = d
+	}
+
+	displayName := os.Getenv("BOT_DISPLAY_NAME")
+	if displayName == "" {
+		displayName = "Meetbot"
+	}
+
+	storagePath, storageKey, err := storageStateConfig()
+	if err != nil {
+		return nil, err
+	}
+
 	return &Bot{
-		headless: headless,
-		email:    email,
-		password: password,
+		headless:         headless,
+		storagePath:      storagePath,
+		storageKey:       storageKey,
+		guest:            guest,
+		email:            email,
+		password:         password,
+		totpKey:          totpKey,
+		displayName:      displayName,
+		admissionTimeout: admissionTimeout,
+		state:            StateIdle,
+		stateSince:       time.Now(),
 	}, nil
 }
 
-func (b *Bot) Initialize() error {
-	// Setup virtual microphone first
+// SetAudioSource selects the PulseAudio source the browser captures as its
+// microphone. It must be called before Initialize.
+func (b *Bot) SetAudioSource(source string) {
+	b.audioSource = source
+}
+
+// browserEnv returns the environment for the browser process, or nil to
+// inherit ours unchanged.
+func (b *Bot) browserEnv() map[string]string {
+	if b.audioSource == "" {
+		return nil
+	}
+
+	env := make(map[string]string)
+	for _, kv := range os.Environ() {
+		parts := strings.SplitN(kv, "=", 2)
+		if len(parts) == 2 {
+			env[parts[0]] = parts[1]
+		}
+	}
+	env["PULSE_SOURCE"] = b.audioSource
+	return env
+}
+
+func (b *Bot) Initialize(ctx context.Context) error {
+	if err := b.initialize(ctx); err != nil {
+		return b.fail(err)
+	}
+	return b.setState(StateBrowserReady)
+}
+
+func (b *Bot) initialize(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	pw, err := playwright.Run()
 	if err != nil {
@@ -680,12 +704,13 @@ func (b *Bot) Initialize() error {
 			"--use-fake-ui-for-media-stream", // Auto-grant microphone permissions
 			"--autoplay-policy=no-user-gesture-required",
 		},
+		Env: b.browserEnv(),
 	}
 
 	log.Printf("[BROWSER_INIT] Attempting to launch Chromium with Docker-optimized settings...")
 
 	// Add timeout to launch options
-	launchOptions.Timeout = playwright.Float(30000) // 30 seconds timeout
+	launchOptions.Timeout = pwTimeout(ctx, 30000) // 30 seconds timeout
 
 	var browser playwright.Browser
 	maxRetries := 3
@@ -698,7 +723,9 @@ func (b *Bot) Initialize() error {
 			log.Printf("[BROWSER_INIT] Attempt %d failed: %v", attempt, err)
 			if attempt < maxRetries {
 				log.Printf("[BROWSER_INIT] Waiting 2 seconds before retry...")
-				time.Sleep(2 * time.Second)
+				if err := sleep(ctx, 2*time.Second); err != nil {
+					return err
+				}
 				continue
 			}
 
@@ -706,7 +733,7 @@ func (b *Bot) Initialize() error {
 			log.Printf("[BROWSER_INIT] All attempts failed, trying minimal fallback...")
 			fallbackOptions := playwright.BrowserTypeLaunchOptions{
 				Headless: playwright.Bool(b.headless),
-				Timeout:  playwright.Float(30000),
+				Timeout:  pwTimeout(ctx, 30000),
 				Args: []string{
 					"--no-sandbox",
 					"--disable-setuid-sandbox",
@@ -718,6 +745,7 @@ func (b *Bot) Initialize() error {
 					"--auto-accept-camera-and-microphone-capture",
 					"--log-level=3",
 				},
+				Env: b.browserEnv(),
 			}
 
 			browser, err = pw.Chromium.Launch(fallbackOptions)
@@ -755,14 +783,26 @@ func (b *Bot) Initialize() error {
 		log.Printf("[BROWSER_INIT] Docker environment detected, configuring for PulseAudio")
 	}
 
+	// Restore cookies and localStorage from the last successful login
+	storageState, err := b.loadStorageState()
+	if err != nil {
+		log.Printf("[STORAGE_STATE] Ignoring saved state: %v", err)
+	} else if storageState != nil {
+		log.Printf("[STORAGE_STATE] Restoring %d cookies from %s", len(storageState.Cookies), b.storagePath)
+		contextOptions.StorageState = storageState
+	}
+
 	log.Printf("[BROWSER_INIT] Creating browser context...")
-	context, err := browser.NewContext(contextOptions)
+	browserContext, err := browser.NewContext(contextOptions)
 	if err != nil {
 		return fmt.Errorf("failed to create browser context: %v", err)
 	}
+	b.storageMu.Lock()
+	b.context = browserContext
+	b.storageMu.Unlock()
 
 	log.Printf("[BROWSER_INIT] Creating new page...")
-	page, err := context.NewPage()
+	page, err := browserContext.NewPage()
 	if err != nil {
 		return fmt.Errorf("failed to create page: %v", err)
 	}
@@ -771,7 +811,7 @@ func (b *Bot) Initialize() error {
 	log.Printf("[BROWSER_INIT] Testing page with simple navigation...")
 	_, err = page.Goto("data:text/html,<html><body><h1>Browser Test</h1></body></html>", playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateLoad,
-		Timeout:   playwright.Float(10000),
+		Timeout:   pwTimeout(ctx, 10000),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to navigate to test page: %v", err)
@@ -781,110 +821,31 @@ func (b *Bot) Initialize() error {
 	b.running = true
 
 	log.Printf("[BROWSER_INIT] Browser initialized successfully with virtual microphone support")
-	log.Printf("[BROWSER_INIT] Virtual microphone path: /tmp/virtmic")
+	if b.audioSource != "" {
+		log.Printf("[BROWSER_INIT] Audio source: %s", b.audioSource)
+	} else {
+		log.Printf("[BROWSER_INIT] Virtual microphone path: /tmp/virtmic")
+	}
 	log.Printf("[BROWSER_INIT] PulseAudio server: %s", os.Getenv("PULSE_SERVER"))
 
 	return nil
 }
 
-func (b *Bot) LeaveMeeting() error {
+func (b *Bot) IsLoggedIn(ctx context.Context) (bool, error) {
 	if !b.running {
-		return fmt.Errorf("bot not initialized")
-	}
-
-	fmt.Println("Attempting to leave the meeting...")
-
-	// Try multiple selectors for the leave button
-	leaveSelectors := []string{
-		"button[aria-label*='Leave call']",
-		"div[data-tooltip*='Leave call']",
-		"button[aria-label*='End call']",
-		"div[data-tooltip*='End call']",
-		"button:has-text('Leave call')",
-		"div[role='button']:has-text('Leave call')",
-		"button[jsname='CQylAd']", // Google Meet specific leave button
-		"div[jsname='CQylAd']",
-	}
-
-	leaveButtonFound := false
-	for _, selector := range leaveSelectors {
-		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
-			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(3000),
-		})
-		if err == nil {
-			fmt.Printf("Found leave button with selector: %s\n", selector)
-			err = b.page.Locator(selector).Click()
-			if err != nil {
-				fmt.Printf("Failed to click leave button: %v\n", err)
-				continue
-			}
-			leaveButtonFound = true
-			break
-		}
-	}
-
-	if !leaveButtonFound {
-		// Try keyboard shortcut as fallback
-		fmt.Println("Leave button not found, trying Ctrl+D shortcut...")
-		err := b.page.Keyboard().Press("Control+d")
-		if err != nil {
-			return fmt.Errorf("could not find leave button and keyboard shortcut failed: %v", err)
-		}
+		return false, ErrNotRunning
 	}
 
-	// Wait for confirmation that we've left
-	fmt.Println("Waiting for meeting exit confirmation...")
-
-	// Look for indicators that we've left the meeting
-	exitSelectors := []string{
-		"text=You left the meeting",
-		"text=Call ended",
-		"text=Meeting ended",
-		"div[aria-label*='left the meeting']",
-		"button:has-text('Rejoin')",
-		"div:has-text('Thanks for joining')",
-	}
-
-	exitConfirmed := false
-	for _, selector := range exitSelectors {
-		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
-			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(5000),
-		})
-		if err == nil {
-			fmt.Println("Successfully left the meeting!")
-			exitConfirmed = true
-			break
-		}
-	}
-
-	if !exitConfirmed {
-		// Check if we're back to a Google page or meeting lobby
-		currentUrl := b.page.URL()
-		if !strings.Contains(currentUrl, "meet.google.com") ||
-			strings.Contains(currentUrl, "thanks") ||
-			strings.Contains(currentUrl, "feedback") {
-			fmt.Println("Meeting left successfully based on URL change")
-			exitConfirmed = true
-		}
-	}
-
-	if !exitConfirmed {
-		fmt.Println("Meeting exit status unclear, but leave command was executed")
-	}
-
-	return nil
-}
-
-func (b *Bot) IsLoggedIn() (bool, error) {
-	if !b.running {
-		return false, fmt.Errorf("bot not initialized")
+	// Checking navigates away from the current page, which would drop us out
+	// of a meeting
+	if b.inState(StateWaitingInLobby, StateInMeeting, StateLeaving) {
+		return false, fmt.Errorf("cannot check login status during a meeting")
 	}
 
 	// Navigate to a Google service to check login status
 	_, err := b.page.Goto("https://accounts.google.com/", playwright.PageGotoOptions{
 		WaitUntil: playwright.WaitUntilStateNetworkidle,
+		Timeout:   pwTimeout(ctx, navigationTimeout),
 	})
 	if err != nil {
 		return false, fmt.Errorf("failed to navigate to Google accounts: %v", err)
@@ -909,10 +870,14 @@ func (b *Bot) IsLoggedIn() (bool, error) {
 	for _, selector := range loggedInSelectors {
 		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
 			State:   playwright.WaitForSelectorStateVisible,
-			Timeout: playwright.Float(3000),
+			Timeout: pwTimeout(ctx, 3000),
 		})
 		if err == nil {
 			fmt.Println("User is already logged in to Google")
+			b.googleSession.Store(true)
+			if b.inState(StateBrowserReady, StateLeft, StateFailed) {
+				b.setState(StateLoggedIn)
+			}
 			return true, nil
 		}
 	}
@@ -920,9 +885,9 @@ func (b *Bot) IsLoggedIn() (bool, error) {
 	return false, nil
 }
 
-func (b *Bot) TakeScreenshot() ([]byte, error) {
+func (b *Bot) TakeScreenshot(ctx context.Context) ([]byte, error) {
 	if !b.running {
-		return nil, fmt.Errorf("bot not initialized")
+		return nil, ErrNotRunning
 	}
 
 	log.Printf("[SCREENSHOT] Taking screenshot...")
@@ -931,6 +896,7 @@ func (b *Bot) TakeScreenshot() ([]byte, error) {
 		FullPage: playwright.Bool(false), // Only visible area
 		Type:     playwright.ScreenshotTypeJpeg,
 		Quality:  playwright.Int(80), // Compress for faster streaming
+		Timeout:  pwTimeout(ctx, navigationTimeout),
 	})
 
 	if err != nil {
@@ -0,0 +1,58 @@
+package bot
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestNewBotGuestModeIsOptIn(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		guest   bool
+		noMeet  bool // Google Meet joins are refused
+		failing bool // NewBot itself fails
+	}{
+		{name: "account", env: "GOOGLE_EMAIL=bot@example.com\nGOOGLE_PASSWORD=secret\n"},
+		{name: "guest mode", env: "BOT_GUEST_MODE=true\n", guest: true},
+		{name: "guest mode with an account", env: "GOOGLE_EMAIL=bot@example.com\nBOT_GUEST_MODE=true\n", guest: true},
+		{name: "no account", env: "BOT_DISPLAY_NAME=Meetbot\n", noMeet: true},
+		{name: "no account, guest mode off", env: "BOT_GUEST_MODE=false\n", noMeet: true},
+		{name: "no password", env: "GOOGLE_EMAIL=bot@example.com\n", failing: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// LoadEnv sets these with os.Setenv; t.Setenv restores them
+			for _, key := range []string{"GOOGLE_EMAIL", "GOOGLE_PASSWORD", "BOT_GUEST_MODE", "BOT_DISPLAY_NAME"} {
+				t.Setenv(key, "")
+			}
+			t.Chdir(t.TempDir())
+			if err := os.WriteFile(".env", []byte(tt.env), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			b, err := NewBot(true)
+			if tt.failing {
+				if err == nil {
+					t.Fatal("NewBot succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewBot: %v", err)
+			}
+			if b.guest != tt.guest {
+				t.Errorf("guest = %v, want %v", b.guest, tt.guest)
+			}
+
+			err = b.requireGoogleAccount()
+			if tt.noMeet != errors.Is(err, ErrNoGoogleAccount) {
+				t.Errorf("requireGoogleAccount = %v, want ErrNoGoogleAccount %v", err, tt.noMeet)
+			}
+			if err := (&googleMeet{b: b}).Login(t.Context()); tt.noMeet != errors.Is(err, ErrNoGoogleAccount) {
+				t.Errorf("Google Meet login = %v, want ErrNoGoogleAccount %v", err, tt.noMeet)
+			}
+		})
+	}
+}
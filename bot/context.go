@@ -0,0 +1,45 @@
+package bot
+
+import (
+	"context"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Playwright calls block without a context, so Bot methods honour theirs in
+// two ways: every wait is capped at the time left before the deadline, and
+// cancellation is checked between steps.
+
+const (
+	navigationTimeout = 30000 // ms, Playwright's default
+	clickTimeout      = 10000 // ms
+)
+
+// pwTimeout returns a Playwright timeout of ms milliseconds, shortened to the
+// time left before ctx's deadline.
+func pwTimeout(ctx context.Context, ms float64) *float64 {
+	if deadline, ok := ctx.Deadline(); ok {
+		left := float64(time.Until(deadline).Milliseconds())
+		if left < 1 {
+			left = 1
+		}
+		if left < ms {
+			ms = left
+		}
+	}
+	return playwright.Float(ms)
+}
+
+// sleep pauses for d, returning early with ctx's error if it is cancelled.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,167 @@
+package bot
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// EventType identifies what happened in an Event.
+type EventType string
+
+const (
+	EventStateChanged   EventType = "state_changed"
+	EventButtonClick    EventType = "button_click"
+	EventPopupDismissed EventType = "popup_dismissed"
+	EventTTSStarted     EventType = "tts_started"
+	EventTTSFinished    EventType = "tts_finished"
+	EventError          EventType = "error"
+
+	// Meeting lifecycle milestones
+	EventLoginSucceeded EventType = "login_succeeded"
+	EventLoginFailed    EventType = "login_failed"
+	EventMeetingJoined  EventType = "meeting_joined"
+	EventJoinFailed     EventType = "join_failed"
+	EventMeetingLeft    EventType = "meeting_left"
+)
+
+// EventTypes lists every event type the bot publishes.
+var EventTypes = []EventType{
+	EventStateChanged,
+	EventButtonClick,
+	EventPopupDismissed,
+	EventTTSStarted,
+	EventTTSFinished,
+	EventError,
+	EventLoginSucceeded,
+	EventLoginFailed,
+	EventMeetingJoined,
+	EventJoinFailed,
+	EventMeetingLeft,
+}
+
+// Event is a single piece of bot activity. Source names the session that
+// produced it.
+type Event struct {
+	ID     uint64         `json:"id"`
+	Type   EventType      `json:"type"`
+	Source string         `json:"source,omitempty"`
+	Time   time.Time      `json:"time"`
+	Data   map[string]any `json:"data,omitempty"`
+}
+
+// eventHistorySize is how many recent events the bus keeps for subscribers
+// that reconnect and ask to catch up.
+const eventHistorySize = 256
+
+// EventBus fans events out to subscribers. Publishing never blocks: a
+// subscriber that falls behind loses events rather than stalling the bot.
+type EventBus struct {
+	mu      sync.Mutex
+	nextID  uint64
+	subs    map[*Subscription]struct{}
+	history []Event
+}
+
+// Subscription receives events published after it was created.
+type Subscription struct {
+	C <-chan Event
+
+	ch      chan Event
+	bus     *EventBus
+	dropped int
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the event an ID and timestamp and delivers it to every
+// subscriber.
+func (bus *EventBus) Publish(e Event) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	bus.nextID++
+	e.ID = bus.nextID
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	bus.history = append(bus.history, e)
+	if len(bus.history) > eventHistorySize {
+		bus.history = bus.history[len(bus.history)-eventHistorySize:]
+	}
+
+	for sub := range bus.subs {
+		select {
+		case sub.ch <- e:
+		default:
+			sub.dropped++
+			if sub.dropped == 1 || sub.dropped%100 == 0 {
+				log.Printf("[EVENTS] Slow subscriber, dropped %d events", sub.dropped)
+			}
+		}
+	}
+}
+
+// Subscribe registers a subscriber with room for buffer pending events.
+func (bus *EventBus) Subscribe(buffer int) *Subscription {
+	ch := make(chan Event, buffer)
+	sub := &Subscription{
+		C:   ch,
+		ch:  ch,
+		bus: bus,
+	}
+
+	bus.mu.Lock()
+	bus.subs[sub] = struct{}{}
+	bus.mu.Unlock()
+
+	return sub
+}
+
+// Since returns the retained events with an ID greater than id, oldest first.
+func (bus *EventBus) Since(id uint64) []Event {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+
+	var events []Event
+	for _, e := range bus.history {
+		if e.ID > id {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Close unsubscribes and closes the channel.
+func (sub *Subscription) Close() {
+	sub.bus.mu.Lock()
+	defer sub.bus.mu.Unlock()
+
+	if _, ok := sub.bus.subs[sub]; ok {
+		delete(sub.bus.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// SetEventBus makes the bot publish its activity to bus, tagged with source.
+func (b *Bot) SetEventBus(bus *EventBus, source string) {
+	b.events = bus
+	b.eventSource = source
+}
+
+// emit publishes an event if the bot has an event bus.
+func (b *Bot) emit(t EventType, data map[string]any) {
+	if b.events == nil {
+		return
+	}
+	b.events.Publish(Event{
+		Type:   t,
+		Source: b.eventSource,
+		Data:   data,
+	})
+}
@@ -0,0 +1,355 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// jitsi drives Jitsi Meet rooms (meet.jit.si or self-hosted) as a guest.
+type jitsi struct {
+	b *Bot
+}
+
+func newJitsi(b *Bot) MeetingPlatform {
+	return &jitsi{b: b}
+}
+
+func (j *jitsi) Name() string {
+	return "jitsi"
+}
+
+// isJitsiURL matches meet.jit.si and the self-hosted servers listed, comma
+// separated, in JITSI_HOSTS. Jitsi can run on any domain, but treating every
+// unknown link as a Jitsi room would hide typos and unsupported platforms.
+func isJitsiURL(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	if host == "meet.jit.si" {
+		return true
+	}
+	for _, h := range strings.Split(os.Getenv("JITSI_HOSTS"), ",") {
+		if h = strings.TrimSpace(h); h != "" && strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Login is a no-op; Jitsi rooms are joined as a guest with a display name.
+func (j *jitsi) Login(ctx context.Context) error {
+	return nil
+}
+
+// Toolbar and dialog selectors shared by several steps. Jitsi labels its
+// toolbar buttons for accessibility, which is steadier than its class names.
+var (
+	jitsiHangupSelectors = []string{
+		"[aria-label='Leave the meeting']",
+		"[aria-label='Leave meeting']",
+		"[aria-label='Hang up']",
+		".hangup-button",
+	}
+	jitsiMutedSelectors = []string{
+		"[aria-label='Unmute microphone']",
+		"[aria-label='Unmute']",
+		"[aria-label='Toggle mute audio'][aria-pressed='true']",
+	}
+	jitsiUnmutedSelectors = []string{
+		"[aria-label='Mute microphone']",
+		"[aria-label='Mute']",
+		"[aria-label='Toggle mute audio'][aria-pressed='false']",
+	}
+	jitsiLobbySelectors = []string{
+		"text=Asking to join meeting",
+		"text=You are waiting in the lobby",
+		"[data-testid='lobby.screen']",
+	}
+	// Moderated rooms show a lobby screen where we knock to be let in
+	jitsiKnockSelectors = []string{
+		"[data-testid='lobby.knockButton']",
+		"[role='button']:has-text('Ask to join')",
+		"button:has-text('Ask to join')",
+	}
+	jitsiRejectedSelectors = []string{
+		"text=rejected your request",
+		"text=request to join was rejected",
+		"text=You have been kicked out",
+	}
+	jitsiPasswordSelectors = []string{
+		"input[name='lockKey']",
+		"input[type='password']",
+		"input[placeholder*='assword']",
+	}
+)
+
+// withJitsiConfig adds URL hash settings Jitsi reads on load: our display
+// name, camera off and the microphone live so the virtual mic is heard.
+// The pre-join screen is still handled below for deployments that ignore
+// hash overrides.
+func withJitsiConfig(meetingURL, displayName string) string {
+	u, err := url.Parse(meetingURL)
+	if err != nil {
+		return meetingURL
+	}
+
+	params := []string{
+		"config.startWithVideoMuted=true",
+		"config.startWithAudioMuted=false",
+	}
+	if displayName != "" {
+		// Jitsi decodes hash values with decodeURIComponent, which leaves
+		// "+" alone, so spaces must be %20. PathEscape would leave "&" and
+		// "=" in the name to split the parameters.
+		name := strings.ReplaceAll(url.QueryEscape(`"`+displayName+`"`), "+", "%20")
+		params = append(params, "userInfo.displayName="+name)
+	}
+	if u.Fragment != "" {
+		params = append([]string{u.Fragment}, params...)
+	}
+	u.Fragment = ""
+	return u.String() + "#" + strings.Join(params, "&")
+}
+
+func (j *jitsi) Join(ctx context.Context, meetingURL string, opts JoinOptions) error {
+	b := j.b
+
+	fmt.Printf("Joining Jitsi meeting: %s\n", meetingURL)
+
+	_, err := b.page.Goto(withJitsiConfig(meetingURL, opts.DisplayName), playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateLoad,
+		Timeout:   pwTimeout(ctx, navigationTimeout),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to navigate to meeting URL: %v", err)
+	}
+
+	if err := b.joinStep(ctx, opts, JoinPhasePreJoin); err != nil {
+		return err
+	}
+
+	b.ClearPopups(ctx)
+
+	// Pre-join screen (newer Jitsi) or a standalone display-name dialog
+	// (older deployments and prejoin disabled)
+	nameSelectors := []string{
+		"[data-testid='prejoin.input']",
+		"input[placeholder='Enter your name']",
+		"input[name='displayName']",
+		"#dialog-form-field",
+	}
+	nameSelector, err := b.findElementFast(ctx, nameSelectors, 3000)
+	if err == nil && opts.DisplayName != "" {
+		fmt.Printf("Found display name input with selector: %s\n", nameSelector)
+		err = b.page.Locator(nameSelector).Fill(opts.DisplayName)
+		if err != nil {
+			return fmt.Errorf("failed to enter display name: %v", err)
+		}
+	}
+
+	// Turn the camera off before joining; the microphone stays on so the
+	// virtual mic is heard
+	prejoinCameraSelectors := []string{
+		"[data-testid='prejoin.videoToggle'][aria-pressed='false']",
+		"[aria-label='Stop camera']",
+		"[aria-label='Turn off camera']",
+	}
+	if selector, err := b.findElementFast(ctx, prejoinCameraSelectors, 1000); err == nil {
+		b.clickWithLogging(ctx, selector, "TOGGLE_CAMERA_OFF", "Jitsi - Pre-join Setup")
+	}
+
+	if err := b.joinStep(ctx, opts, JoinPhaseAskingToJoin); err != nil {
+		return err
+	}
+
+	joinSelectors := []string{
+		"[data-testid='prejoin.joinMeeting']",
+		"div[role='button']:has-text('Join meeting')",
+		"button:has-text('Join meeting')",
+		"button:has-text('Join')",
+		"#modal-dialog-ok-button",
+	}
+	joinSelector, err := b.findElementFast(ctx, joinSelectors, 3000)
+	if err == nil {
+		err = b.clickWithLogging(ctx, joinSelector, "JOIN_MEETING", "Jitsi - Join Meeting")
+		if err != nil {
+			return fmt.Errorf("failed to click join button: %v", err)
+		}
+	} else if nameSelector != "" {
+		// The name dialog submits on Enter when it has no visible button
+		log.Printf("[KEYBOARD_ACTION] Pressing Enter to submit Jitsi display name")
+		b.page.Keyboard().Press("Enter")
+	}
+
+	if err := b.setState(StateWaitingInLobby); err != nil {
+		return err
+	}
+
+	fmt.Println("Waiting for Jitsi meeting to load...")
+
+	// Password protected rooms prompt after the join click
+	if selector, err := b.findElementFast(ctx, jitsiPasswordSelectors, 2000); err == nil {
+		if opts.Password == "" {
+			return fmt.Errorf("meeting room is password protected and no password was given")
+		}
+		fmt.Println("Room is password protected, entering password...")
+		err = b.page.Locator(selector).Fill(opts.Password)
+		if err != nil {
+			return fmt.Errorf("failed to enter room password: %v", err)
+		}
+		okSelectors := []string{
+			"#modal-dialog-ok-button",
+			"button:has-text('OK')",
+			"button:has-text('Join')",
+		}
+		if err := b.clickFirstVisible(ctx, okSelectors, 1000, "SUBMIT_PASSWORD", "Jitsi - Room Password"); err != nil {
+			b.page.Keyboard().Press("Enter")
+		}
+		if _, err := b.findElementFast(ctx, []string{"text=Wrong password", "text=incorrect password"}, 1500); err == nil {
+			return fmt.Errorf("meeting room password was rejected")
+		}
+	}
+
+	// Wait for the meeting, or for the lobby of a moderated room
+	waitFor := strings.Join(append(slices.Clone(jitsiHangupSelectors), jitsiKnockSelectors...), ", ")
+	b.findElementFast(ctx, []string{waitFor}, 5000)
+
+	if b.isVisibleAny(jitsiHangupSelectors) {
+		fmt.Println("Successfully joined the Jitsi meeting!")
+		return b.admitted(ctx)
+	}
+
+	if b.isVisibleAny(jitsiKnockSelectors) {
+		if err := j.knock(ctx, opts); err != nil {
+			return err
+		}
+	}
+
+	if b.isVisibleAny(jitsiLobbySelectors) {
+		fmt.Println("Waiting in the Jitsi lobby for a moderator to admit us...")
+		return nil
+	}
+
+	fmt.Println("Jitsi meeting UI not detected yet, still waiting for admission...")
+	return nil
+}
+
+// knock asks the moderators of a lobby-enabled room to let us in. The lobby
+// screen has its own name field, and the button stays disabled while it is
+// empty.
+func (j *jitsi) knock(ctx context.Context, opts JoinOptions) error {
+	b := j.b
+
+	lobbyName := b.page.Locator("[data-testid='lobby.nameField']").First()
+	if visible, _ := lobbyName.IsVisible(); visible && opts.DisplayName != "" {
+		if value, _ := lobbyName.InputValue(); value == "" {
+			if err := lobbyName.Fill(opts.DisplayName); err != nil {
+				return fmt.Errorf("failed to enter display name in the lobby: %v", err)
+			}
+		}
+	}
+
+	err := b.clickFirstVisible(ctx, jitsiKnockSelectors, 2000, "ASK_TO_JOIN", "Jitsi - Lobby")
+	if err != nil {
+		return fmt.Errorf("failed to ask to join from the lobby: %v", err)
+	}
+	return nil
+}
+
+func (j *jitsi) Leave(ctx context.Context) error {
+	b := j.b
+
+	fmt.Println("Attempting to leave the Jitsi meeting...")
+
+	err := b.clickFirstVisible(ctx, jitsiHangupSelectors, 3000, "LEAVE_MEETING", "Jitsi - Meeting Controls")
+	if err != nil {
+		// Lobby screens have their own cancel button
+		cancelSelectors := []string{
+			"[data-testid='lobby.cancel']",
+			"button:has-text('Cancel')",
+		}
+		if err := b.clickFirstVisible(ctx, cancelSelectors, 1000, "CANCEL_KNOCK", "Jitsi - Lobby"); err != nil {
+			return fmt.Errorf("could not find leave button")
+		}
+	}
+
+	// Newer Jitsi asks whether to leave or end the meeting for everyone
+	confirmSelectors := []string{
+		"button:has-text('Leave meeting')",
+		"[aria-label='Leave meeting']:not([aria-pressed])",
+	}
+	b.clickFirstVisible(ctx, confirmSelectors, 1000, "CONFIRM_LEAVE", "Jitsi - Leave Dialog")
+
+	if _, err := b.findElementFast(ctx, jitsiHangupSelectors, 1000); err == nil {
+		fmt.Println("Jitsi meeting exit status unclear, but leave command was executed")
+		return nil
+	}
+
+	fmt.Println("Successfully left the Jitsi meeting!")
+	return nil
+}
+
+func (j *jitsi) SetMic(ctx context.Context, on bool) error {
+	if on {
+		err := j.b.clickFirstVisible(ctx, jitsiMutedSelectors, 2000, "ENABLE_MICROPHONE", "Jitsi - Meeting Controls")
+		if err != nil {
+			return fmt.Errorf("could not find microphone enable button")
+		}
+		return nil
+	}
+
+	err := j.b.clickFirstVisible(ctx, jitsiUnmutedSelectors, 2000, "DISABLE_MICROPHONE", "Jitsi - Meeting Controls")
+	if err != nil {
+		return fmt.Errorf("could not find microphone disable button")
+	}
+	return nil
+}
+
+func (j *jitsi) SetCamera(ctx context.Context, on bool) error {
+	if on {
+		cameraSelectors := []string{
+			"[aria-label='Start camera']",
+			"[aria-label='Toggle mute video'][aria-pressed='true']",
+		}
+		err := j.b.clickFirstVisible(ctx, cameraSelectors, 2000, "ENABLE_CAMERA", "Jitsi - Meeting Controls")
+		if err != nil {
+			return fmt.Errorf("could not find camera enable button")
+		}
+		return nil
+	}
+
+	cameraSelectors := []string{
+		"[aria-label='Stop camera']",
+		"[aria-label='Toggle mute video'][aria-pressed='false']",
+	}
+	err := j.b.clickFirstVisible(ctx, cameraSelectors, 2000, "DISABLE_CAMERA", "Jitsi - Meeting Controls")
+	if err != nil {
+		return fmt.Errorf("could not find camera disable button")
+	}
+	return nil
+}
+
+func (j *jitsi) DetectState(ctx context.Context) (*MeetingStatus, error) {
+	b := j.b
+
+	status := &MeetingStatus{
+		URL:       b.page.URL(),
+		InMeeting: b.isVisibleAny(jitsiHangupSelectors),
+		Rejected:  b.visibleText(jitsiRejectedSelectors),
+	}
+
+	if b.isVisibleAny(jitsiMutedSelectors) {
+		muted := true
+		status.MicrophoneMuted = &muted
+	} else if b.isVisibleAny(jitsiUnmutedSelectors) {
+		muted := false
+		status.MicrophoneMuted = &muted
+	}
+
+	return status, nil
+}
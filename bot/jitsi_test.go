@@ -0,0 +1,202 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+func TestWithJitsiConfig(t *testing.T) {
+	tests := []struct {
+		url, name, want string
+	}{
+		{
+			"https://meet.jit.si/Standup", "Meet Bot",
+			"https://meet.jit.si/Standup#config.startWithVideoMuted=true&config.startWithAudioMuted=false&userInfo.displayName=%22Meet%20Bot%22",
+		},
+		{
+			"https://jitsi.example.com/room", "R&D = 1+1",
+			"https://jitsi.example.com/room#config.startWithVideoMuted=true&config.startWithAudioMuted=false&userInfo.displayName=%22R%26D%20%3D%201%2B1%22",
+		},
+		{
+			"https://jitsi.example.com/room#config.prejoinConfig.enabled=false", "",
+			"https://jitsi.example.com/room#config.prejoinConfig.enabled=false&config.startWithVideoMuted=true&config.startWithAudioMuted=false",
+		},
+	}
+	for _, tt := range tests {
+		if got := withJitsiConfig(tt.url, tt.name); got != tt.want {
+			t.Errorf("withJitsiConfig(%q, %q)\n got %s\nwant %s", tt.url, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIsJitsiURL(t *testing.T) {
+	t.Setenv("JITSI_HOSTS", " jitsi.example.com, Meet.Example.org ,")
+
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://meet.jit.si/Standup", true},
+		{"https://MEET.JIT.SI/Standup", true},
+		{"https://jitsi.example.com/room", true},
+		{"https://jitsi.example.com:8443/room", true},
+		{"https://meet.example.org/room", true},
+		{"https://meet.jit.si.evil.example/Standup", false},
+		{"https://evil.meet.jit.si.example/Standup", false},
+		{"https://other.jitsi.example.com/room", false},
+		{"https://example.com/room", false},
+		{"https://meet.google.com/abc-defg-hij", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isJitsiURL(u); got != tt.want {
+			t.Errorf("isJitsiURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+
+	// Without JITSI_HOSTS only meet.jit.si is Jitsi, and an empty host
+	// does not match the empty list entry
+	t.Setenv("JITSI_HOSTS", "")
+	for _, rawURL := range []string{"https://jitsi.example.com/room", "file:///room"} {
+		u, _ := url.Parse(rawURL)
+		if isJitsiURL(u) {
+			t.Errorf("isJitsiURL(%q) = true without JITSI_HOSTS", rawURL)
+		}
+	}
+}
+
+func TestPlatformFor(t *testing.T) {
+	t.Setenv("JITSI_HOSTS", "jitsi.example.com")
+
+	tests := []struct {
+		url, want string
+	}{
+		{"https://meet.google.com/abc-defg-hij", "google_meet"},
+		{"https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0", "teams"},
+		{"https://us02web.zoom.us/j/1234567890", "zoom"},
+		{"https://meet.jit.si/Standup", "jitsi"},
+		{"https://jitsi.example.com/room", "jitsi"},
+	}
+	b := &Bot{}
+	for _, tt := range tests {
+		platform, err := b.platformFor(tt.url)
+		if err != nil || platform.Name() != tt.want {
+			t.Errorf("platformFor(%q) = %v, %v, want %s", tt.url, platform, err, tt.want)
+		}
+	}
+
+	for _, rawURL := range []string{"https://example.com/room", "https://meet.jit.si.evil.example/room"} {
+		if platform, err := b.platformFor(rawURL); err == nil || !strings.Contains(err.Error(), "unsupported meeting platform") {
+			t.Errorf("platformFor(%q) = %v, %v, want unsupported", rawURL, platform, err)
+		}
+	}
+}
+
+// fixtureBot returns a bot driving a headless browser, skipping the test
+// when Playwright and its browsers are not installed.
+func fixtureBot(t *testing.T) *Bot {
+	t.Helper()
+	pw, err := playwright.Run()
+	if err != nil {
+		t.Skipf("Playwright is not installed: %v", err)
+	}
+	t.Cleanup(func() { pw.Stop() })
+
+	browser, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{Headless: playwright.Bool(true)})
+	if err != nil {
+		t.Skipf("Chromium is not installed: %v", err)
+	}
+	t.Cleanup(func() { browser.Close() })
+
+	page, err := browser.NewPage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Bot{
+		pw:               pw,
+		browser:          browser,
+		page:             page,
+		running:          true,
+		displayName:      "Meet Bot & Co",
+		admissionTimeout: 10 * time.Second,
+		state:            StateBrowserReady,
+		stateSince:       time.Now(),
+	}
+}
+
+func serveFixture(t *testing.T, file string) string {
+	t.Helper()
+	server := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	t.Cleanup(server.Close)
+	return server.URL + "/" + file
+}
+
+func TestJitsiJoinFixture(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		knocks bool
+		err    error
+	}{
+		{name: "open room"},
+		{name: "lobby", query: "?lobby=admit", knocks: true},
+		{name: "lobby denied", query: "?lobby=deny", knocks: true, err: ErrAdmissionDenied},
+	}
+	t.Setenv("JITSI_HOSTS", "127.0.0.1")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := fixtureBot(t)
+			room := serveFixture(t, "jitsi.html") + tt.query
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+			defer cancel()
+			err := b.Join(ctx, room, JoinOptions{})
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("Join = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Join: %v", err)
+			}
+			if state := b.State().State; state != StateInMeeting {
+				t.Fatalf("state = %s, want %s", state, StateInMeeting)
+			}
+			if _, ok := b.currentPlatform().(*jitsi); !ok {
+				t.Fatalf("platform = %s, want jitsi", b.currentPlatform().Name())
+			}
+
+			knocked, _ := b.page.Evaluate("window.knocked")
+			if knocked != tt.knocks {
+				t.Errorf("knocked = %v, want %v", knocked, tt.knocks)
+			}
+			shown, _ := b.page.Locator("#displayName").TextContent()
+			if shown != "Meet Bot & Co" {
+				t.Errorf("display name from the URL = %q, want %q", shown, "Meet Bot & Co")
+			}
+			typed, _ := b.page.Evaluate("window.joinedAs")
+			if typed != "Meet Bot & Co" {
+				t.Errorf("display name typed = %v, want %q", typed, "Meet Bot & Co")
+			}
+
+			if err := b.LeaveMeeting(ctx); err != nil {
+				t.Fatalf("LeaveMeeting: %v", err)
+			}
+			if left, _ := b.page.Locator("#left").IsVisible(); !left {
+				t.Error("the hang up button was not clicked")
+			}
+		})
+	}
+}
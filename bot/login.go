@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Sentinel errors for the ways a Google login can fail. GoogleLogin wraps
+// them, so callers can use errors.Is to decide whether to retry or get a
+// human to look at the account.
+var (
+	ErrWrongPassword     = errors.New("wrong password")
+	ErrUnknownAccount    = errors.New("google account not found")
+	ErrCaptcha           = errors.New("captcha required")
+	ErrTwoFactorRequired = errors.New("2-step verification required")
+	ErrAccountRecovery   = errors.New("account recovery prompt")
+	ErrBrowserNotSecure  = errors.New("browser rejected as not secure")
+	ErrLoginUnconfirmed  = errors.New("login could not be confirmed")
+	ErrNoGoogleAccount   = errors.New("no Google account configured")
+)
+
+// LoginOutcome classifies the result of GoogleLogin.
+type LoginOutcome string
+
+const (
+	LoginSuccess           LoginOutcome = "success"
+	LoginWrongPassword     LoginOutcome = "wrong_password"
+	LoginUnknownAccount    LoginOutcome = "unknown_account"
+	LoginCaptcha           LoginOutcome = "captcha"
+	LoginTwoFactorRequired LoginOutcome = "two_factor_required"
+	LoginAccountRecovery   LoginOutcome = "account_recovery"
+	LoginBrowserNotSecure  LoginOutcome = "browser_not_secure"
+	LoginUnknown           LoginOutcome = "unknown"
+)
+
+var loginOutcomes = []struct {
+	err     error
+	outcome LoginOutcome
+}{
+	{ErrWrongPassword, LoginWrongPassword},
+	{ErrUnknownAccount, LoginUnknownAccount},
+	{ErrCaptcha, LoginCaptcha},
+	{ErrTwoFactorRequired, LoginTwoFactorRequired},
+	{ErrAccountRecovery, LoginAccountRecovery},
+	{ErrBrowserNotSecure, LoginBrowserNotSecure},
+}
+
+// LoginResult describes how a GoogleLogin attempt ended.
+type LoginResult struct {
+	Outcome   LoginOutcome `json:"outcome"`
+	Challenge Challenge    `json:"challenge,omitempty"` // set for captcha and 2FA outcomes
+	Message   string       `json:"message,omitempty"`
+	URL       string       `json:"url"` // page the login ended on
+	// Retryable is true when trying again may help. The other failures need
+	// someone to fix the credentials or sign in to the account by hand.
+	Retryable bool `json:"retryable"`
+}
+
+func newLoginResult(err error, pageURL string) *LoginResult {
+	result := &LoginResult{Outcome: LoginSuccess, URL: pageURL}
+	if err == nil {
+		return result
+	}
+
+	result.Outcome = LoginUnknown
+	result.Message = err.Error()
+	for _, o := range loginOutcomes {
+		if errors.Is(err, o.err) {
+			result.Outcome = o.outcome
+			break
+		}
+	}
+	var challengeErr *ChallengeError
+	if errors.As(err, &challengeErr) {
+		result.Challenge = challengeErr.Challenge
+	}
+	result.Retryable = result.Outcome == LoginUnknown
+	return result
+}
+
+// loginStep is the sign-in page a failure check runs on; the same error
+// banner means different things on each.
+type loginStep int
+
+const (
+	loginStepEmail loginStep = iota
+	loginStepPassword
+)
+
+// googleErrorSelector is the error banner under the sign-in form fields.
+const googleErrorSelector = "[jsname='B34EJ'] span"
+
+var (
+	browserNotSecureSelectors = []string{
+		"text=This browser or app may not be secure",
+		"text=Couldn't sign you in",
+		"text=Couldn’t sign you in",
+	}
+	accountRecoverySelectors = []string{
+		"text=Confirm your recovery email",
+		"text=Confirm your recovery phone number",
+		"text=Make sure you can always sign in",
+		"text=Protect your account",
+	}
+	unknownAccountSelectors = []string{
+		"text=find your Google Account",
+		"text=Enter a valid email or phone number",
+	}
+	wrongPasswordSelectors = []string{
+		"text=Wrong password",
+		"text=Your password was changed",
+	}
+)
+
+// loginFailure inspects the current sign-in page for the known reasons a
+// login is stuck and returns the matching wrapped sentinel error, or nil.
+func (b *Bot) loginFailure(step loginStep) error {
+	currentUrl := b.page.URL()
+
+	if strings.Contains(currentUrl, "/signin/rejected") || b.isVisibleAny(browserNotSecureSelectors) {
+		return fmt.Errorf("%w: google refused to sign in from this browser", ErrBrowserNotSecure)
+	}
+	if b.isVisibleAny(captchaSelectors) {
+		return &ChallengeError{Challenge: ChallengeCaptcha, URL: currentUrl}
+	}
+	if strings.Contains(currentUrl, "speedbump") || strings.Contains(currentUrl, "recoveryoptions") ||
+		b.isVisibleAny(accountRecoverySelectors) {
+		return fmt.Errorf("%w: google wants the account's recovery details confirmed", ErrAccountRecovery)
+	}
+
+	message := ""
+	if visible, err := b.page.Locator(googleErrorSelector).First().IsVisible(); err == nil && visible {
+		message, _ = b.page.Locator(googleErrorSelector).First().TextContent()
+		message = strings.TrimSpace(message)
+	}
+
+	switch step {
+	case loginStepEmail:
+		if b.isVisibleAny(unknownAccountSelectors) || message != "" {
+			return fmt.Errorf("%w: %s", ErrUnknownAccount, orDefault(message, b.email))
+		}
+	case loginStepPassword:
+		if b.isVisibleAny(wrongPasswordSelectors) || message != "" {
+			return fmt.Errorf("%w: %s", ErrWrongPassword, orDefault(message, "password rejected"))
+		}
+	}
+	return nil
+}
+
+// signedIn reports whether a URL is outside the sign-in pages, which Google
+// only lets a browser reach once the login is complete.
+func signedIn(pageURL string) bool {
+	u, err := url.Parse(pageURL)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	return host != "accounts.google.com" && host != "gds.google.com"
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,444 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// googleMeet drives meet.google.com with a signed-in Google account, or as a
+// signed-out guest in guest mode.
+type googleMeet struct {
+	b *Bot
+}
+
+func newGoogleMeet(b *Bot) MeetingPlatform {
+	return &googleMeet{b: b}
+}
+
+func (m *googleMeet) Name() string {
+	return "google_meet"
+}
+
+// Login signs in to Google unless the browser already has a session.
+func (m *googleMeet) Login(ctx context.Context) error {
+	if m.b.guest {
+		fmt.Println("Guest mode, skipping Google login...")
+		return nil
+	}
+	if err := m.b.requireGoogleAccount(); err != nil {
+		return err
+	}
+
+	// Already confirmed this run; an expired session still gets caught by
+	// the sign-in redirect in Join
+	if m.b.googleSession.Load() {
+		fmt.Println("Google session already verified, skipping login check...")
+		return nil
+	}
+
+	loggedIn, err := m.b.IsLoggedIn(ctx)
+	if err != nil {
+		fmt.Printf("Error checking login status: %v\n", err)
+	}
+
+	if loggedIn {
+		fmt.Println("Already logged in, skipping login...")
+		return nil
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	fmt.Println("Not logged in, performing login...")
+	_, err = m.b.GoogleLogin(ctx)
+	return err
+}
+
+func (m *googleMeet) Join(ctx context.Context, meetingURL string, opts JoinOptions) error {
+	b := m.b
+
+	fmt.Printf("Joining Google Meet: %s\n", meetingURL)
+
+	// Navigate to the meeting URL
+	_, err := b.page.Goto(meetingURL, playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateNetworkidle,
+		Timeout:   pwTimeout(ctx, navigationTimeout),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to navigate to meeting URL: %v", err)
+	}
+
+	// Wait for the page to load
+	err = b.page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+		State:   playwright.LoadStateNetworkidle,
+		Timeout: pwTimeout(ctx, navigationTimeout),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to wait for page load: %v", err)
+	}
+
+	// Check if we need to login first
+	currentUrl := b.page.URL()
+	if strings.Contains(currentUrl, "accounts.google.com") && strings.Contains(currentUrl, "signin") {
+		if b.guest {
+			return fmt.Errorf("meeting does not allow guests, a Google account is required")
+		}
+		fmt.Println("Need to login first...")
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		_, err = b.GoogleLogin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to login before joining meeting: %v", err)
+		}
+
+		if err := b.setState(StatePreJoin); err != nil {
+			return err
+		}
+
+		// Navigate back to meeting after login
+		_, err = b.page.Goto(meetingURL, playwright.PageGotoOptions{
+			WaitUntil: playwright.WaitUntilStateNetworkidle,
+			Timeout:   pwTimeout(ctx, navigationTimeout),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to navigate back to meeting after login: %v", err)
+		}
+	}
+
+	if err := b.joinStep(ctx, opts, JoinPhasePreJoin); err != nil {
+		return err
+	}
+
+	// Clear any popups that might interfere
+	b.ClearPopups(ctx)
+
+	// Signed-out guests are asked for a name before they can ask to join
+	if b.guest {
+		nameSelectors := []string{
+			"input[aria-label='Your name']",
+			"input[placeholder='Your name']",
+			"input[type='text'][jsname]",
+		}
+		nameSelector, err := b.findElementFast(ctx, nameSelectors, 5000)
+		if err != nil {
+			return fmt.Errorf("failed to find the guest name field")
+		}
+		fmt.Printf("Entering guest name %q\n", opts.DisplayName)
+		err = b.page.Locator(nameSelector).Fill(opts.DisplayName)
+		if err != nil {
+			return fmt.Errorf("failed to enter guest name: %v", err)
+		}
+	}
+
+	// Handle microphone and camera permissions
+	fmt.Println("Handling microphone and camera settings...")
+
+	// Try to turn off camera
+	cameraSelectors := []string{
+		"div[data-tooltip*='camera']",
+		"button[aria-label*='camera']",
+		"div[aria-label*='Turn off camera']",
+		"button[data-tooltip*='Turn off camera']",
+	}
+
+	cameraSelector, err := b.findElementFast(ctx, cameraSelectors, 1000)
+	if err == nil {
+		fmt.Printf("Found camera button with selector: %s\n", cameraSelector)
+		b.clickWithLogging(ctx, cameraSelector, "TOGGLE_CAMERA_OFF", "Google Meet - Pre-join Setup")
+	}
+
+	// Try to turn off microphone initially (we'll control it via virtual mic)
+	micSelectors := []string{
+		"div[data-tooltip*='microphone']",
+		"button[aria-label*='microphone']",
+		"div[aria-label*='Turn off microphone']",
+		"button[data-tooltip*='Turn off microphone']",
+	}
+
+	micSelector, err := b.findElementFast(ctx, micSelectors, 1000)
+	if err == nil {
+		fmt.Printf("Found microphone button with selector: %s\n", micSelector)
+		b.clickWithLogging(ctx, micSelector, "TOGGLE_MIC_OFF", "Google Meet - Pre-join Setup")
+	}
+
+	// Clear popups again after handling camera/microphone
+	b.ClearPopups(ctx)
+
+	if err := b.joinStep(ctx, opts, JoinPhaseAskingToJoin); err != nil {
+		return err
+	}
+
+	// Look for and click the "Join now" button with retry logic
+	fmt.Println("Looking for join button...")
+	joinSelectors := []string{
+		"button:has-text('Join now')",
+		"div[role='button']:has-text('Join now')",
+		"button:has-text('Ask to join')",
+		"div[role='button']:has-text('Ask to join')",
+		"button[aria-label*='Join']",
+		"div[data-tooltip*='Join']",
+	}
+
+	joinButtonFound := false
+	maxRetries := 2
+
+	for retry := 0; retry < maxRetries && !joinButtonFound; retry++ {
+		if retry > 0 {
+			fmt.Printf("Retry attempt %d for join button...\n", retry)
+			b.ClearPopups(ctx) // Clear popups before retry
+		}
+
+		for _, selector := range joinSelectors {
+			err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
+				State:   playwright.WaitForSelectorStateVisible,
+				Timeout: pwTimeout(ctx, 1500),
+			})
+			if err == nil {
+				fmt.Printf("Found join button with selector: %s\n", selector)
+				err = b.clickWithLogging(ctx, selector, "JOIN_MEETING", "Google Meet - Join Meeting")
+				if err != nil {
+					log.Printf("[JOIN_BUTTON_ERROR] Failed to click join button on attempt %d: %v", retry+1, err)
+					if retry < maxRetries-1 {
+						continue // Try next selector or retry
+					}
+					return fmt.Errorf("failed to click join button after %d attempts: %v", maxRetries, err)
+				}
+				joinButtonFound = true
+				break
+			}
+		}
+	}
+
+	if !joinButtonFound {
+		return fmt.Errorf("could not find join button after %d attempts", maxRetries)
+	}
+
+	// Until the meeting UI shows up we may be waiting for the host to admit us
+	if err := b.setState(StateWaitingInLobby); err != nil {
+		return err
+	}
+
+	// Wait for meeting to load
+	fmt.Println("Waiting for meeting to load...")
+
+	// Wait for meeting interface elements
+	meetingSelectors := []string{
+		"div[data-allocation-index]", // Meeting participants area
+		"div[jsname='HzV7m']",        // Meeting controls
+		"button[aria-label*='Leave call']",
+		"div[aria-label*='You joined']",
+	}
+
+	meetingJoined := false
+	for _, selector := range meetingSelectors {
+		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
+			State:   playwright.WaitForSelectorStateVisible,
+			Timeout: pwTimeout(ctx, 3000), // Reduced from 15000ms to 3000ms
+		})
+		if err == nil {
+			fmt.Println("Successfully joined the meeting!")
+			meetingJoined = true
+			break
+		}
+	}
+
+	if !meetingJoined {
+		fmt.Println("Meeting UI not detected yet, still waiting for admission...")
+		return nil
+	}
+
+	return b.admitted(ctx)
+}
+
+func (m *googleMeet) SetMic(ctx context.Context, on bool) error {
+	if on {
+		micSelectors := []string{
+			"button[aria-label*='Turn on microphone']",
+			"div[data-tooltip*='Turn on microphone']",
+			"button[aria-label*='Unmute']",
+			"div[aria-label*='Unmute']",
+		}
+		err := m.b.clickFirstVisible(ctx, micSelectors, 2000, "ENABLE_MICROPHONE", "Google Meet - Meeting Controls")
+		if err != nil {
+			return fmt.Errorf("could not find microphone enable button")
+		}
+		return nil
+	}
+
+	micSelectors := []string{
+		"button[aria-label*='Turn off microphone']",
+		"div[data-tooltip*='Turn off microphone']",
+		"button[aria-label*='Mute']:not([aria-label*='Unmute'])",
+		"div[aria-label*='Mute']:not([aria-label*='Unmute'])",
+	}
+	err := m.b.clickFirstVisible(ctx, micSelectors, 2000, "DISABLE_MICROPHONE", "Google Meet - Meeting Controls")
+	if err != nil {
+		return fmt.Errorf("could not find microphone disable button")
+	}
+	return nil
+}
+
+func (m *googleMeet) SetCamera(ctx context.Context, on bool) error {
+	if on {
+		cameraSelectors := []string{
+			"button[aria-label*='Turn on camera']",
+			"div[data-tooltip*='Turn on camera']",
+		}
+		err := m.b.clickFirstVisible(ctx, cameraSelectors, 2000, "ENABLE_CAMERA", "Google Meet - Meeting Controls")
+		if err != nil {
+			return fmt.Errorf("could not find camera enable button")
+		}
+		return nil
+	}
+
+	cameraSelectors := []string{
+		"button[aria-label*='Turn off camera']",
+		"div[data-tooltip*='Turn off camera']",
+	}
+	err := m.b.clickFirstVisible(ctx, cameraSelectors, 2000, "DISABLE_CAMERA", "Google Meet - Meeting Controls")
+	if err != nil {
+		return fmt.Errorf("could not find camera disable button")
+	}
+	return nil
+}
+
+func (m *googleMeet) Leave(ctx context.Context) error {
+	b := m.b
+
+	fmt.Println("Attempting to leave the meeting...")
+
+	// Try multiple selectors for the leave button
+	leaveSelectors := []string{
+		"button[aria-label*='Leave call']",
+		"div[data-tooltip*='Leave call']",
+		"button[aria-label*='End call']",
+		"div[data-tooltip*='End call']",
+		"button:has-text('Leave call')",
+		"div[role='button']:has-text('Leave call')",
+		"button[jsname='CQylAd']", // Google Meet specific leave button
+		"div[jsname='CQylAd']",
+	}
+
+	leaveButtonFound := false
+	for _, selector := range leaveSelectors {
+		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
+			State:   playwright.WaitForSelectorStateVisible,
+			Timeout: pwTimeout(ctx, 3000),
+		})
+		if err == nil {
+			fmt.Printf("Found leave button with selector: %s\n", selector)
+			err = b.page.Locator(selector).Click(playwright.LocatorClickOptions{
+				Timeout: pwTimeout(ctx, clickTimeout),
+			})
+			if err != nil {
+				fmt.Printf("Failed to click leave button: %v\n", err)
+				continue
+			}
+			leaveButtonFound = true
+			break
+		}
+	}
+
+	if !leaveButtonFound {
+		// Try keyboard shortcut as fallback
+		fmt.Println("Leave button not found, trying Ctrl+D shortcut...")
+		err := b.page.Keyboard().Press("Control+d")
+		if err != nil {
+			return fmt.Errorf("could not find leave button and keyboard shortcut failed: %v", err)
+		}
+	}
+
+	// Wait for confirmation that we've left
+	fmt.Println("Waiting for meeting exit confirmation...")
+
+	// Look for indicators that we've left the meeting
+	exitSelectors := []string{
+		"text=You left the meeting",
+		"text=Call ended",
+		"text=Meeting ended",
+		"div[aria-label*='left the meeting']",
+		"button:has-text('Rejoin')",
+		"div:has-text('Thanks for joining')",
+	}
+
+	exitConfirmed := false
+	for _, selector := range exitSelectors {
+		err := b.page.Locator(selector).WaitFor(playwright.LocatorWaitForOptions{
+			State:   playwright.WaitForSelectorStateVisible,
+			Timeout: pwTimeout(ctx, 5000),
+		})
+		if err == nil {
+			fmt.Println("Successfully left the meeting!")
+			exitConfirmed = true
+			break
+		}
+	}
+
+	if !exitConfirmed {
+		// Check if we're back to a Google page or meeting lobby
+		currentUrl := b.page.URL()
+		if !strings.Contains(currentUrl, "meet.google.com") ||
+			strings.Contains(currentUrl, "thanks") ||
+			strings.Contains(currentUrl, "feedback") {
+			fmt.Println("Meeting left successfully based on URL change")
+			exitConfirmed = true
+		}
+	}
+
+	if !exitConfirmed {
+		fmt.Println("Meeting exit status unclear, but leave command was executed")
+	}
+
+	return nil
+}
+
+// meetDeniedSelectors are shown instead of the meeting when the host
+// declines, or nobody answers, an "Ask to join" request.
+var meetDeniedSelectors = []string{
+	"text=Someone in the call denied your request",
+	"text=denied your request to join",
+	"text=You can't join this call",
+	"text=You can’t join this call",
+	"text=No one responded to your request",
+}
+
+func (m *googleMeet) DetectState(ctx context.Context) (*MeetingStatus, error) {
+	b := m.b
+
+	status := &MeetingStatus{
+		URL:      b.page.URL(),
+		Rejected: b.visibleText(meetDeniedSelectors),
+	}
+
+	inMeetingSelectors := []string{
+		"button[aria-label*='Leave call']",
+		"div[data-tooltip*='Leave call']",
+		"button[jsname='CQylAd']",
+	}
+	status.InMeeting = strings.Contains(status.URL, "meet.google.com") && b.isVisibleAny(inMeetingSelectors)
+
+	mutedSelectors := []string{
+		"button[aria-label*='Turn on microphone']",
+		"div[data-tooltip*='Turn on microphone']",
+	}
+	unmutedSelectors := []string{
+		"button[aria-label*='Turn off microphone']",
+		"div[data-tooltip*='Turn off microphone']",
+	}
+
+	if b.isVisibleAny(mutedSelectors) {
+		muted := true
+		status.MicrophoneMuted = &muted
+	} else if b.isVisibleAny(unmutedSelectors) {
+		muted := false
+		status.MicrophoneMuted = &muted
+	}
+
+	return status, nil
+}
@@ -0,0 +1,339 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MeetingPlatform drives one video conferencing product in the bot's browser.
+// Implementations record lobby and admission through the bot's state machine;
+// Bot takes care of the surrounding transitions and events.
+type MeetingPlatform interface {
+	// Name identifies the platform in status output, e.g. "google_meet".
+	Name() string
+
+	// Login signs in to whatever account the platform needs. Platforms that
+	// join as a guest return nil.
+	Login(ctx context.Context) error
+
+	// Join opens the meeting URL, gets through the pre-join screen and asks
+	// to be let in. It leaves the bot WaitingInLobby or InMeeting; Bot then
+	// waits for admission through DetectState. Implementations report their
+	// progress and check for cancellation through Bot.joinStep.
+	Join(ctx context.Context, meetingURL string, opts JoinOptions) error
+
+	// Leave hangs up.
+	Leave(ctx context.Context) error
+
+	SetMic(ctx context.Context, on bool) error
+	SetCamera(ctx context.Context, on bool) error
+
+	// DetectState reports what the page currently shows, including whether
+	// the host turned down our request to join. The State field is filled
+	// in by Bot.
+	DetectState(ctx context.Context) (*MeetingStatus, error)
+}
+
+// admissionHandler is implemented by platforms that need to act once the
+// bot is let into the meeting, whether that happens during Join or later.
+type admissionHandler interface {
+	onAdmitted(ctx context.Context)
+}
+
+// rejectionClassifier is implemented by platforms whose rejection messages
+// cover more than the host denying the request. classifyRejection returns
+// the sentinel for a MeetingStatus.Rejected text, or nil for a denial.
+type rejectionClassifier interface {
+	classifyRejection(rejected string) error
+}
+
+// JoinOptions carries per-meeting details that are not part of the URL.
+type JoinOptions struct {
+	// DisplayName is shown to other participants where the platform asks
+	// for one. Empty uses the bot's configured display name.
+	DisplayName string
+
+	// Password for rooms that ask for one.
+	Password string
+
+	// AdmissionTimeout bounds the wait in a lobby or waiting room. Zero uses
+	// the bot's configured timeout.
+	AdmissionTimeout time.Duration
+
+	// Progress, if set, is called as the join moves from phase to phase.
+	Progress func(phase JoinPhase)
+
+	// PageLock, if set, is the lock the caller holds around Join to keep
+	// other users off the page. Join releases it between checks while
+	// waiting for admission, which can take minutes, and holds it again
+	// before it returns.
+	PageLock sync.Locker
+}
+
+// JoinPhase is a step of Bot.Join, in the order they happen.
+type JoinPhase string
+
+const (
+	JoinPhaseLoggingIn           JoinPhase = "logging_in"
+	JoinPhaseNavigating          JoinPhase = "navigating"
+	JoinPhasePreJoin             JoinPhase = "pre_join"
+	JoinPhaseAskingToJoin        JoinPhase = "asking_to_join"
+	JoinPhaseWaitingForAdmission JoinPhase = "waiting_for_admission"
+	JoinPhaseJoined              JoinPhase = "joined"
+)
+
+// joinStep marks the start of a join phase. It returns the context's error
+// if the join was cancelled, so callers stop before the next browser action.
+func (b *Bot) joinStep(ctx context.Context, opts JoinOptions, phase JoinPhase) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	log.Printf("[JOIN] Phase: %s", phase)
+	if opts.Progress != nil {
+		opts.Progress(phase)
+	}
+	return nil
+}
+
+// platformDriver registers a platform implementation with the URLs it handles.
+type platformDriver struct {
+	name    string
+	matches func(u *url.URL) bool
+	new     func(b *Bot) MeetingPlatform
+}
+
+// platformDrivers is consulted in order; the first match wins.
+var platformDrivers = []platformDriver{
+	{
+		name: "google_meet",
+		matches: func(u *url.URL) bool {
+			return strings.EqualFold(u.Hostname(), "meet.google.com")
+		},
+		new: newGoogleMeet,
+	},
+	{
+		name:    "teams",
+		matches: isTeamsURL,
+		new:     newTeams,
+	},
+	{
+		name:    "zoom",
+		matches: isZoomURL,
+		new:     newZoom,
+	},
+	{
+		name:    "jitsi",
+		matches: isJitsiURL,
+		new:     newJitsi,
+	},
+}
+
+// platformFor picks the platform implementation for a meeting URL.
+func (b *Bot) platformFor(meetingURL string) (MeetingPlatform, error) {
+	u, err := url.Parse(meetingURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid meeting URL: %q", meetingURL)
+	}
+
+	for _, driver := range platformDrivers {
+		if driver.matches(u) {
+			return driver.new(b), nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported meeting platform for %s", u.Host)
+}
+
+// currentPlatform returns the platform of the last meeting joined, falling
+// back to Google Meet before the first join.
+func (b *Bot) currentPlatform() MeetingPlatform {
+	if b.platform == nil {
+		b.platform = newGoogleMeet(b)
+	}
+	return b.platform
+}
+
+// Join joins the meeting at meetingURL, choosing the platform from the URL and
+// logging in first if the platform needs it. Cancelling ctx stops the join
+// between steps and withdraws any request to be let in.
+func (b *Bot) Join(ctx context.Context, meetingURL string, opts JoinOptions) error {
+	if !b.running {
+		return ErrNotRunning
+	}
+
+	if b.inState(StateWaitingInLobby, StateInMeeting, StateLeaving) {
+		return fmt.Errorf("already in a meeting (state %s)", b.State().State)
+	}
+
+	platform, err := b.platformFor(meetingURL)
+	if err != nil {
+		return err
+	}
+	b.platform = platform
+	fmt.Printf("Using %s platform for %s\n", platform.Name(), meetingURL)
+
+	if err := b.joinStep(ctx, opts, JoinPhaseLoggingIn); err != nil {
+		return err
+	}
+	if err := platform.Login(ctx); err != nil {
+		return fmt.Errorf("failed to login: %w", err)
+	}
+
+	if err := b.setState(StatePreJoin); err != nil {
+		return err
+	}
+	if opts.DisplayName == "" {
+		opts.DisplayName = b.displayName
+	}
+	if opts.AdmissionTimeout <= 0 {
+		opts.AdmissionTimeout = b.admissionTimeout
+	}
+
+	err = b.joinStep(ctx, opts, JoinPhaseNavigating)
+	if err == nil {
+		err = platform.Join(ctx, meetingURL, opts)
+	}
+	if err == nil && b.inState(StateWaitingInLobby) {
+		err = b.joinStep(ctx, opts, JoinPhaseWaitingForAdmission)
+		if err == nil {
+			err = b.waitForAdmission(ctx, opts.AdmissionTimeout, opts.PageLock)
+		}
+	}
+	if err == nil {
+		b.joinStep(context.Background(), opts, JoinPhaseJoined)
+		return nil
+	}
+
+	// The bot was closed under a join waiting in a lobby; there is no page
+	// left to clean up or state to record
+	if errors.Is(err, ErrNotRunning) {
+		return err
+	}
+
+	// Don't leave a half-joined page behind a cancelled join
+	if ctx.Err() != nil {
+		b.page.Goto("about:blank")
+	}
+	b.emit(EventJoinFailed, map[string]any{
+		"url":      meetingURL,
+		"platform": platform.Name(),
+		"error":    err.Error(),
+	})
+	return b.fail(err)
+}
+
+// admitted records that the meeting UI is visible, i.e. we are in the call.
+func (b *Bot) admitted(ctx context.Context) error {
+	if err := b.setState(StateInMeeting); err != nil {
+		return err
+	}
+	if h, ok := b.currentPlatform().(admissionHandler); ok {
+		h.onAdmitted(ctx)
+	}
+	b.emit(EventMeetingJoined, map[string]any{
+		"url":      b.page.URL(),
+		"platform": b.currentPlatform().Name(),
+	})
+	return nil
+}
+
+func (b *Bot) LeaveMeeting(ctx context.Context) error {
+	if !b.running {
+		return ErrNotRunning
+	}
+
+	if err := b.setState(StateLeaving); err != nil {
+		return fmt.Errorf("not in a meeting: %v", err)
+	}
+	if err := b.currentPlatform().Leave(ctx); err != nil {
+		return b.fail(err)
+	}
+	if err := b.setState(StateLeft); err != nil {
+		return err
+	}
+	b.emit(EventMeetingLeft, map[string]any{"platform": b.currentPlatform().Name()})
+	return nil
+}
+
+func (b *Bot) EnableMicrophone(ctx context.Context) error {
+	if !b.running {
+		return ErrNotRunning
+	}
+	return b.currentPlatform().SetMic(ctx, true)
+}
+
+func (b *Bot) DisableMicrophone(ctx context.Context) error {
+	if !b.running {
+		return ErrNotRunning
+	}
+	return b.currentPlatform().SetMic(ctx, false)
+}
+
+// SetCamera turns the camera on or off in the current meeting.
+func (b *Bot) SetCamera(ctx context.Context, on bool) error {
+	if !b.running {
+		return ErrNotRunning
+	}
+	return b.currentPlatform().SetCamera(ctx, on)
+}
+
+// MeetingStatus describes what the bot currently sees in the browser.
+// MicrophoneMuted is nil when the microphone button could not be found.
+type MeetingStatus struct {
+	URL             string `json:"url"`
+	Platform        string `json:"platform,omitempty"`
+	InMeeting       bool   `json:"inMeeting"`
+	Rejected        string `json:"rejected,omitempty"` // why the host turned us away, if they did
+	MicrophoneMuted *bool  `json:"microphoneMuted,omitempty"`
+	State           State  `json:"state"`
+}
+
+func (b *Bot) MeetingStatus(ctx context.Context) (*MeetingStatus, error) {
+	if !b.running {
+		return nil, ErrNotRunning
+	}
+
+	platform := b.currentPlatform()
+	status, err := platform.DetectState(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status.Platform = platform.Name()
+	status.State = b.State().State
+
+	// The host may have admitted us since Join returned
+	if status.InMeeting && status.State == StateWaitingInLobby {
+		if err := b.admitted(ctx); err == nil {
+			status.State = StateInMeeting
+		}
+	}
+
+	return status, nil
+}
+
+// isVisibleAny reports whether any of the selectors currently matches a
+// visible element. Unlike findElementFast it does not wait.
+func (b *Bot) isVisibleAny(selectors []string) bool {
+	for _, selector := range selectors {
+		visible, err := b.page.Locator(selector).First().IsVisible()
+		if err == nil && visible {
+			return true
+		}
+	}
+	return false
+}
+
+// clickFirstVisible waits up to timeout ms for each selector in turn and
+// clicks the first one that shows up.
+func (b *Bot) clickFirstVisible(ctx context.Context, selectors []string, timeout int, action, clickContext string) error {
+	selector, err := b.findElementFast(ctx, selectors, timeout)
+	if err != nil {
+		return err
+	}
+	return b.clickWithLogging(ctx, selector, action, clickContext)
+}
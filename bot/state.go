@@ -0,0 +1,147 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// State is where the bot is in its meeting lifecycle.
+type State string
+
+const (
+	StateIdle           State = "idle"
+	StateBrowserReady   State = "browser_ready"
+	StateLoggingIn      State = "logging_in"
+	StateLoggedIn       State = "logged_in"
+	StatePreJoin        State = "pre_join"
+	StateWaitingInLobby State = "waiting_in_lobby"
+	StateInMeeting      State = "in_meeting"
+	StateLeaving        State = "leaving"
+	StateLeft           State = "left"
+	StateFailed         State = "failed"
+)
+
+// validTransitions lists the states reachable from each state. Every state
+// may also move to Idle (browser closed) and Failed.
+var validTransitions = map[State][]State{
+	StateIdle:           {StateBrowserReady},
+	StateBrowserReady:   {StateLoggingIn, StateLoggedIn, StatePreJoin},
+	StateLoggingIn:      {StateLoggedIn},
+	StateLoggedIn:       {StateLoggingIn, StatePreJoin},
+	StatePreJoin:        {StateLoggingIn, StateWaitingInLobby, StateInMeeting},
+	StateWaitingInLobby: {StateInMeeting, StateLeaving, StatePreJoin},
+	StateInMeeting:      {StateLeaving},
+	StateLeaving:        {StateLeft},
+	StateLeft:           {StateLoggingIn, StateLoggedIn, StatePreJoin},
+	StateFailed:         {StateBrowserReady, StateLoggingIn, StateLoggedIn, StatePreJoin, StateLeaving},
+}
+
+// maxTransitionHistory bounds the transitions kept in StateInfo.
+const maxTransitionHistory = 32
+
+// Transition records a single state change.
+type Transition struct {
+	From State     `json:"from"`
+	To   State     `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// StateInfo is a snapshot of the bot's lifecycle state.
+type StateInfo struct {
+	State       State        `json:"state"`
+	Since       time.Time    `json:"since"`
+	LastError   string       `json:"lastError,omitempty"`
+	LastErrorAt *time.Time   `json:"lastErrorAt,omitempty"`
+	Transitions []Transition `json:"transitions"`
+}
+
+func canTransition(from, to State) bool {
+	if to == StateIdle || to == StateFailed {
+		return true
+	}
+	for _, s := range validTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// State returns a snapshot of the current lifecycle state. It is safe to call
+// concurrently with other Bot methods.
+func (b *Bot) State() StateInfo {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	info := StateInfo{
+		State:       b.state,
+		Since:       b.stateSince,
+		Transitions: append([]Transition(nil), b.transitions...),
+	}
+	if b.lastErr != nil {
+		info.LastError = b.lastErr.Error()
+		at := b.lastErrAt
+		info.LastErrorAt = &at
+	}
+	return info
+}
+
+// setState moves the bot to a new state, rejecting transitions the lifecycle
+// does not allow.
+func (b *Bot) setState(to State) error {
+	b.stateMu.Lock()
+
+	from := b.state
+	if from == to {
+		b.stateMu.Unlock()
+		return nil
+	}
+	if !canTransition(from, to) {
+		b.stateMu.Unlock()
+		log.Printf("[STATE] Rejected transition %s -> %s", from, to)
+		return fmt.Errorf("cannot move from %s to %s", from, to)
+	}
+
+	now := time.Now()
+	b.state = to
+	b.stateSince = now
+	b.transitions = append(b.transitions, Transition{From: from, To: to, At: now})
+	if len(b.transitions) > maxTransitionHistory {
+		b.transitions = b.transitions[len(b.transitions)-maxTransitionHistory:]
+	}
+	b.stateMu.Unlock()
+
+	log.Printf("[STATE] %s -> %s", from, to)
+	b.emit(EventStateChanged, map[string]any{
+		"from": from,
+		"to":   to,
+	})
+	return nil
+}
+
+// fail records err and moves the bot to Failed. It returns err so callers can
+// write `return b.fail(err)`.
+func (b *Bot) fail(err error) error {
+	b.stateMu.Lock()
+	b.lastErr = err
+	b.lastErrAt = time.Now()
+	b.stateMu.Unlock()
+
+	b.emit(EventError, map[string]any{"error": err.Error()})
+	b.setState(StateFailed)
+	return err
+}
+
+// inState reports whether the bot is currently in one of the given states.
+func (b *Bot) inState(states ...State) bool {
+	b.stateMu.Lock()
+	defer b.stateMu.Unlock()
+
+	for _, s := range states {
+		if b.state == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"errors"
+	"testing"
+)
+
+var allStates = []State{
+	StateIdle, StateBrowserReady, StateLoggingIn, StateLoggedIn, StatePreJoin,
+	StateWaitingInLobby, StateInMeeting, StateLeaving, StateLeft, StateFailed,
+}
+
+func TestCanTransition(t *testing.T) {
+	// Spelled out rather than read from validTransitions, so a change to
+	// the lifecycle has to be made in both places
+	allowed := map[State][]State{
+		StateIdle:           {StateBrowserReady},
+		StateBrowserReady:   {StateLoggingIn, StateLoggedIn, StatePreJoin},
+		StateLoggingIn:      {StateLoggedIn},
+		StateLoggedIn:       {StateLoggingIn, StatePreJoin},
+		StatePreJoin:        {StateLoggingIn, StateWaitingInLobby, StateInMeeting},
+		StateWaitingInLobby: {StateInMeeting, StateLeaving, StatePreJoin},
+		StateInMeeting:      {StateLeaving},
+		StateLeaving:        {StateLeft},
+		StateLeft:           {StateLoggingIn, StateLoggedIn, StatePreJoin},
+		StateFailed:         {StateBrowserReady, StateLoggingIn, StateLoggedIn, StatePreJoin, StateLeaving},
+	}
+	if len(validTransitions) != len(allStates) {
+		t.Errorf("validTransitions covers %d states, want %d", len(validTransitions), len(allStates))
+	}
+
+	for _, from := range allStates {
+		for _, to := range allStates {
+			want := to == StateIdle || to == StateFailed
+			for _, s := range allowed[from] {
+				want = want || s == to
+			}
+			if got := canTransition(from, to); got != want {
+				t.Errorf("canTransition(%s, %s) = %v, want %v", from, to, got, want)
+			}
+		}
+	}
+}
+
+func TestSetState(t *testing.T) {
+	b := &Bot{state: StateIdle}
+
+	for _, to := range []State{StateBrowserReady, StatePreJoin, StateWaitingInLobby, StateInMeeting, StateLeaving, StateLeft} {
+		if err := b.setState(to); err != nil {
+			t.Fatalf("setState(%s): %v", to, err)
+		}
+	}
+	// Staying put is not a transition
+	if err := b.setState(StateLeft); err != nil {
+		t.Fatalf("setState(%s) again: %v", StateLeft, err)
+	}
+
+	info := b.State()
+	if info.State != StateLeft || len(info.Transitions) != 6 {
+		t.Fatalf("State() = %s with %d transitions, want %s with 6", info.State, len(info.Transitions), StateLeft)
+	}
+	if first := info.Transitions[0]; first.From != StateIdle || first.To != StateBrowserReady {
+		t.Errorf("first transition = %s -> %s, want %s -> %s", first.From, first.To, StateIdle, StateBrowserReady)
+	}
+
+	for _, tt := range []struct{ from, to State }{
+		{StateIdle, StateInMeeting},
+		{StateInMeeting, StatePreJoin},
+		{StateLeft, StateInMeeting},
+		{StateLoggingIn, StateWaitingInLobby},
+	} {
+		b := &Bot{state: tt.from}
+		if err := b.setState(tt.to); err == nil {
+			t.Errorf("setState(%s -> %s) succeeded, want it rejected", tt.from, tt.to)
+		}
+		if info := b.State(); info.State != tt.from || len(info.Transitions) != 0 {
+			t.Errorf("rejected %s -> %s left the bot in %s with %d transitions", tt.from, tt.to, info.State, len(info.Transitions))
+		}
+	}
+}
+
+func TestStateHistoryBounded(t *testing.T) {
+	b := &Bot{state: StateBrowserReady}
+	for i := 0; i < maxTransitionHistory; i++ {
+		b.setState(StateLoggingIn)
+		b.setState(StateLoggedIn)
+	}
+	if got := len(b.State().Transitions); got != maxTransitionHistory {
+		t.Errorf("kept %d transitions, want %d", got, maxTransitionHistory)
+	}
+}
+
+func TestFail(t *testing.T) {
+	b := &Bot{state: StateInMeeting}
+	errJoin := errors.New("join failed")
+
+	if err := b.fail(errJoin); err != errJoin {
+		t.Fatalf("fail() = %v, want %v", err, errJoin)
+	}
+	info := b.State()
+	if info.State != StateFailed || info.LastError != errJoin.Error() || info.LastErrorAt == nil {
+		t.Errorf("State() = %+v, want %s with the error recorded", info, StateFailed)
+	}
+}
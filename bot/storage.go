@@ -0,0 +1,224 @@
+package bot
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// The browser's cookies and localStorage are kept between restarts so the
+// bot does not have to type its password (and trip Google's suspicious login
+// checks) every time the container starts. The file is encrypted with
+// AES-256-GCM under STORAGE_STATE_KEY, 32 random bytes given as hex or
+// base64; without a key nothing is persisted.
+
+const defaultStorageStatePath = "storage_state.enc"
+
+// storageKeySize is the AES-256 key length STORAGE_STATE_KEY must decode to.
+const storageKeySize = 32
+
+// storageStateConfig reads the persistence settings from the environment.
+func storageStateConfig() (path string, key []byte, err error) {
+	path = os.Getenv("STORAGE_STATE_PATH")
+	if path == "" {
+		path = defaultStorageStatePath
+	}
+	if secret := os.Getenv("STORAGE_STATE_KEY"); secret != "" {
+		key, err = parseStorageKey(secret)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid STORAGE_STATE_KEY: %v", err)
+		}
+	}
+	return path, key, nil
+}
+
+// parseStorageKey decodes a hex or base64 key. The key is used as is, not
+// derived from a passphrase, so anything but 32 bytes is refused rather than
+// stretched.
+func parseStorageKey(secret string) ([]byte, error) {
+	secret = strings.TrimSpace(secret)
+	decoders := []func(string) ([]byte, error){
+		hex.DecodeString,
+		base64.StdEncoding.DecodeString,
+		base64.RawStdEncoding.DecodeString,
+		base64.URLEncoding.DecodeString,
+		base64.RawURLEncoding.DecodeString,
+	}
+	for _, decode := range decoders {
+		if key, err := decode(secret); err == nil && len(key) == storageKeySize {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("must be %d random bytes encoded as hex or base64, e.g. from `openssl rand -hex %d`", storageKeySize, storageKeySize)
+}
+
+func newStorageCipher(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealStorageState encrypts plaintext under key, prefixing the random nonce.
+func sealStorageState(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newStorageCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openStorageState reverses sealStorageState. A wrong key and a damaged file
+// both fail authentication.
+func openStorageState(key, sealed []byte) ([]byte, error) {
+	gcm, err := newStorageCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %v", err)
+	}
+	if len(sealed) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, fmt.Errorf("storage state file is corrupt")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt storage state (wrong STORAGE_STATE_KEY?): %v", err)
+	}
+	return plaintext, nil
+}
+
+// saveStorageState writes the context's storage state to the encrypted file.
+// The write goes through a temporary file so sessions saving at the same time
+// never leave a truncated file behind.
+func (b *Bot) saveStorageState() error {
+	if b.storageKey == nil {
+		return nil
+	}
+
+	// Only the snapshot needs the browser context; encrypting and writing
+	// happen without holding it
+	b.storageMu.Lock()
+	if b.context == nil {
+		b.storageMu.Unlock()
+		return nil
+	}
+	state, err := b.context.StorageState()
+	b.storageMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to read storage state: %v", err)
+	}
+	plaintext, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to encode storage state: %v", err)
+	}
+
+	sealed, err := sealStorageState(b.storageKey, plaintext)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(b.storagePath), ".storage-state-*")
+	if err != nil {
+		return fmt.Errorf("failed to create storage state file: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(sealed); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write storage state: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write storage state: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), b.storagePath); err != nil {
+		return fmt.Errorf("failed to replace storage state: %v", err)
+	}
+
+	log.Printf("[STORAGE_STATE] Saved %d cookies to %s", len(state.Cookies), b.storagePath)
+	return nil
+}
+
+// loadStorageState decrypts the saved storage state. It returns nil without
+// an error when persistence is disabled or nothing has been saved yet.
+func (b *Bot) loadStorageState() (*playwright.OptionalStorageState, error) {
+	if b.storageKey == nil {
+		return nil, nil
+	}
+
+	sealed, err := os.ReadFile(b.storagePath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read storage state: %v", err)
+	}
+
+	plaintext, err := openStorageState(b.storageKey, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	var state playwright.StorageState
+	if err := json.Unmarshal(plaintext, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode storage state: %v", err)
+	}
+
+	optional := &playwright.OptionalStorageState{
+		Origins: state.Origins,
+	}
+	for _, cookie := range state.Cookies {
+		optional.Cookies = append(optional.Cookies, cookie.ToOptionalCookie())
+	}
+	return optional, nil
+}
+
+// RemoveStorageState deletes the saved storage state so the next browser
+// start has to log in again.
+func RemoveStorageState() error {
+	path := os.Getenv("STORAGE_STATE_PATH")
+	if path == "" {
+		path = defaultStorageStatePath
+	}
+	err := os.Remove(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove storage state: %v", err)
+	}
+	log.Printf("[STORAGE_STATE] Removed %s", path)
+	return nil
+}
+
+// ClearCookies drops the running browser's cookies, signing it out of Google
+// and every meeting platform. It does nothing before Initialize. It does not
+// use the page, so callers need not wait for a join to let go of it.
+func (b *Bot) ClearCookies(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.storageMu.Lock()
+	defer b.storageMu.Unlock()
+
+	b.googleSession.Store(false)
+	if b.context == nil {
+		return nil
+	}
+	if err := b.context.ClearCookies(); err != nil {
+		return fmt.Errorf("failed to clear cookies: %v", err)
+	}
+	return nil
+}
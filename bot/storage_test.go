@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func testStorageKey(fill byte) []byte {
+	return bytes.Repeat([]byte{fill}, storageKeySize)
+}
+
+func TestParseStorageKey(t *testing.T) {
+	key := make([]byte, storageKeySize)
+	for i := range key {
+		key[i] = byte(i * 7)
+	}
+
+	valid := []string{
+		hex.EncodeToString(key),
+		strings.ToUpper(hex.EncodeToString(key)),
+		base64.StdEncoding.EncodeToString(key),
+		base64.RawStdEncoding.EncodeToString(key),
+		base64.URLEncoding.EncodeToString(key),
+		base64.RawURLEncoding.EncodeToString(key),
+		" " + hex.EncodeToString(key) + "\n",
+	}
+	for _, secret := range valid {
+		got, err := parseStorageKey(secret)
+		if err != nil || !bytes.Equal(got, key) {
+			t.Errorf("parseStorageKey(%q) = %x, %v; want the key", secret, got, err)
+		}
+	}
+
+	invalid := []string{
+		"long-random-secret",
+		"correct horse battery staple",
+		hex.EncodeToString(key[:16]),
+		hex.EncodeToString(append(key, 0)),
+		base64.StdEncoding.EncodeToString(key[:31]),
+		hex.EncodeToString(key)[:63] + "g",
+	}
+	for _, secret := range invalid {
+		if _, err := parseStorageKey(secret); err == nil {
+			t.Errorf("parseStorageKey(%q) accepted a key that is not 32 bytes", secret)
+		}
+	}
+}
+
+func TestStorageStateRoundTrip(t *testing.T) {
+	key := testStorageKey(1)
+	plaintext := []byte(`{"cookies":[{"name":"SID","value":"secret"}],"origins":[]}`)
+
+	sealed, err := sealStorageState(key, plaintext)
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if bytes.Contains(sealed, []byte("secret")) {
+		t.Error("sealed state contains the plaintext")
+	}
+	got, err := openStorageState(key, sealed)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Errorf("opened %q, want %q", got, plaintext)
+	}
+
+	// A fresh nonce each time
+	again, _ := sealStorageState(key, plaintext)
+	if bytes.Equal(again, sealed) {
+		t.Error("sealing twice gave the same ciphertext")
+	}
+}
+
+func TestOpenStorageStateRejects(t *testing.T) {
+	key := testStorageKey(1)
+	sealed, err := sealStorageState(key, []byte(`{"cookies":[]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := bytes.Clone(sealed)
+	tampered[len(tampered)-20] ^= 0x01
+	nonceTampered := bytes.Clone(sealed)
+	nonceTampered[0] ^= 0x80
+
+	tests := []struct {
+		name   string
+		key    []byte
+		sealed []byte
+	}{
+		{"wrong key", testStorageKey(2), sealed},
+		{"truncated", key, sealed[:len(sealed)-1]},
+		{"nonce only", key, sealed[:12]},
+		{"empty", key, nil},
+		{"tampered ciphertext", key, tampered},
+		{"tampered nonce", key, nonceTampered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, err := openStorageState(tt.key, tt.sealed); err == nil {
+				t.Errorf("opened %q, want an error", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// teams drives the Microsoft Teams web client, joining as an anonymous guest.
+type teams struct {
+	b *Bot
+}
+
+func newTeams(b *Bot) MeetingPlatform {
+	return &teams{b: b}
+}
+
+func (t *teams) Name() string {
+	return "teams"
+}
+
+// isTeamsURL matches meeting links for Teams work/school and personal
+// accounts, including regional subdomains.
+func isTeamsURL(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	return host == "teams.microsoft.com" || host == "teams.live.com" ||
+		strings.HasSuffix(host, ".teams.microsoft.com")
+}
+
+// Login is a no-op; the bot joins Teams meetings as a guest.
+func (t *teams) Login(ctx context.Context) error {
+	return nil
+}
+
+var (
+	teamsHangupSelectors = []string{
+		"button#hangup-button",
+		"button[data-tid='hangup-main-btn']",
+		"button[data-tid='call-hangup']",
+		"button[aria-label='Leave']",
+		"button[aria-label*='Hang up']",
+	}
+	teamsMutedSelectors = []string{
+		"button#microphone-button[aria-label*='Unmute']",
+		"button[data-tid='microphone-button'][aria-label*='Unmute']",
+		"button[aria-label^='Unmute']",
+	}
+	teamsUnmutedSelectors = []string{
+		"button#microphone-button[aria-label*='Mute']:not([aria-label*='Unmute'])",
+		"button[data-tid='microphone-button'][aria-label*='Mute']:not([aria-label*='Unmute'])",
+		"button[aria-label^='Mute']",
+	}
+	teamsLobbySelectors = []string{
+		"text=Someone in the meeting should let you in soon",
+		"text=we'll let people know you're waiting",
+		"[data-tid='lobby-screen']",
+	}
+	teamsDeniedSelectors = []string{
+		"text=you were denied access to the meeting",
+		"text=Sorry, but you were denied access",
+		"text=Nobody responded to your request to join",
+	}
+)
+
+func (t *teams) Join(ctx context.Context, meetingURL string, opts JoinOptions) error {
+	b := t.b
+
+	fmt.Printf("Joining Teams meeting: %s\n", meetingURL)
+
+	_, err := b.page.Goto(meetingURL, playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateLoad,
+		Timeout:   pwTimeout(ctx, navigationTimeout),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to navigate to meeting URL: %v", err)
+	}
+
+	// The launcher page offers the desktop app first
+	browserSelectors := []string{
+		"button[data-tid='joinOnWeb']",
+		"button:has-text('Continue on this browser')",
+		"a:has-text('Continue on this browser')",
+		"button:has-text('Join on the web instead')",
+	}
+	err = b.clickFirstVisible(ctx, browserSelectors, 10000, "CONTINUE_IN_BROWSER", "Teams - Launcher")
+	if err != nil {
+		fmt.Println("No launcher page, assuming web client is already loading...")
+	}
+
+	if err := b.joinStep(ctx, opts, JoinPhasePreJoin); err != nil {
+		return err
+	}
+
+	b.ClearPopups(ctx)
+
+	// Guest name on the pre-join screen
+	nameSelectors := []string{
+		"input[data-tid='prejoin-display-name-input']",
+		"input[placeholder='Type your name']",
+		"input[placeholder*='name']",
+	}
+	nameSelector, err := b.findElementFast(ctx, nameSelectors, 15000)
+	if err != nil {
+		return fmt.Errorf("failed to find the guest name field")
+	}
+	if opts.DisplayName != "" {
+		err = b.page.Locator(nameSelector).Fill(opts.DisplayName)
+		if err != nil {
+			return fmt.Errorf("failed to enter display name: %v", err)
+		}
+	}
+
+	// Camera off, microphone on so the virtual mic is heard. The pre-join
+	// toggles are switches whose checked state reflects the device.
+	cameraOnSelectors := []string{
+		"[data-tid='toggle-video'][aria-checked='true']",
+		"div[title='Turn camera off']",
+		"input[title*='camera'][aria-checked='true']",
+	}
+	if selector, err := b.findElementFast(ctx, cameraOnSelectors, 1000); err == nil {
+		b.clickWithLogging(ctx, selector, "TOGGLE_CAMERA_OFF", "Teams - Pre-join Setup")
+	}
+
+	micOffSelectors := []string{
+		"[data-tid='toggle-mute'][aria-checked='false']",
+		"div[title='Unmute microphone']",
+		"input[title*='microphone'][aria-checked='false']",
+	}
+	if selector, err := b.findElementFast(ctx, micOffSelectors, 1000); err == nil {
+		b.clickWithLogging(ctx, selector, "TOGGLE_MIC_ON", "Teams - Pre-join Setup")
+	}
+
+	if err := b.joinStep(ctx, opts, JoinPhaseAskingToJoin); err != nil {
+		return err
+	}
+
+	joinSelectors := []string{
+		"button[data-tid='prejoin-join-button']",
+		"button:has-text('Join now')",
+	}
+	err = b.clickFirstVisible(ctx, joinSelectors, 3000, "JOIN_MEETING", "Teams - Join Meeting")
+	if err != nil {
+		return fmt.Errorf("could not find join button")
+	}
+
+	if err := b.setState(StateWaitingInLobby); err != nil {
+		return err
+	}
+
+	fmt.Println("Waiting for Teams meeting to load...")
+
+	if _, err := b.findElementFast(ctx, teamsHangupSelectors, 5000); err == nil && !b.isVisibleAny(teamsLobbySelectors) {
+		fmt.Println("Successfully joined the Teams meeting!")
+		return b.admitted(ctx)
+	}
+
+	if denied := b.visibleText(teamsDeniedSelectors); denied != "" {
+		return fmt.Errorf("%w: %s", ErrAdmissionDenied, denied)
+	}
+
+	if b.isVisibleAny(teamsLobbySelectors) {
+		fmt.Println("Waiting in the Teams lobby for someone to let us in...")
+		return nil
+	}
+
+	fmt.Println("Teams meeting UI not detected yet, still waiting for admission...")
+	return nil
+}
+
+func (t *teams) Leave(ctx context.Context) error {
+	b := t.b
+
+	fmt.Println("Attempting to leave the Teams meeting...")
+
+	// The lobby screen shows the same hang up button as the meeting
+	err := b.clickFirstVisible(ctx, teamsHangupSelectors, 3000, "LEAVE_MEETING", "Teams - Meeting Controls")
+	if err != nil {
+		fmt.Println("Leave button not found, trying Ctrl+Shift+H shortcut...")
+		err = b.page.Keyboard().Press("Control+Shift+H")
+		if err != nil {
+			return fmt.Errorf("could not find leave button and keyboard shortcut failed: %v", err)
+		}
+	}
+
+	exitSelectors := []string{
+		"text=You left the meeting",
+		"text=Thanks for joining",
+		"[data-tid='call-ended-screen']",
+		"button:has-text('Rejoin')",
+	}
+	if _, err := b.findElementFast(ctx, exitSelectors, 5000); err == nil {
+		fmt.Println("Successfully left the Teams meeting!")
+	} else {
+		fmt.Println("Teams meeting exit status unclear, but leave command was executed")
+	}
+
+	return nil
+}
+
+func (t *teams) SetMic(ctx context.Context, on bool) error {
+	if on {
+		err := t.b.clickFirstVisible(ctx, teamsMutedSelectors, 2000, "ENABLE_MICROPHONE", "Teams - Meeting Controls")
+		if err != nil {
+			return fmt.Errorf("could not find microphone enable button")
+		}
+		return nil
+	}
+
+	err := t.b.clickFirstVisible(ctx, teamsUnmutedSelectors, 2000, "DISABLE_MICROPHONE", "Teams - Meeting Controls")
+	if err != nil {
+		return fmt.Errorf("could not find microphone disable button")
+	}
+	return nil
+}
+
+func (t *teams) SetCamera(ctx context.Context, on bool) error {
+	if on {
+		cameraSelectors := []string{
+			"button#video-button[aria-label*='Turn camera on']",
+			"button[aria-label^='Turn camera on']",
+		}
+		err := t.b.clickFirstVisible(ctx, cameraSelectors, 2000, "ENABLE_CAMERA", "Teams - Meeting Controls")
+		if err != nil {
+			return fmt.Errorf("could not find camera enable button")
+		}
+		return nil
+	}
+
+	cameraSelectors := []string{
+		"button#video-button[aria-label*='Turn camera off']",
+		"button[aria-label^='Turn camera off']",
+	}
+	err := t.b.clickFirstVisible(ctx, cameraSelectors, 2000, "DISABLE_CAMERA", "Teams - Meeting Controls")
+	if err != nil {
+		return fmt.Errorf("could not find camera disable button")
+	}
+	return nil
+}
+
+func (t *teams) DetectState(ctx context.Context) (*MeetingStatus, error) {
+	b := t.b
+
+	status := &MeetingStatus{
+		URL:       b.page.URL(),
+		InMeeting: b.isVisibleAny(teamsHangupSelectors) && !b.isVisibleAny(teamsLobbySelectors),
+		Rejected:  b.visibleText(teamsDeniedSelectors),
+	}
+
+	if b.isVisibleAny(teamsMutedSelectors) {
+		muted := true
+		status.MicrophoneMuted = &muted
+	} else if b.isVisibleAny(teamsUnmutedSelectors) {
+		muted := false
+		status.MicrophoneMuted = &muted
+	}
+
+	return status, nil
+}
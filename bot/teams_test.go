@@ -0,0 +1,36 @@
+package bot
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestIsTeamsURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0?context=%7b%22Tid%22%3a%22x%22%7d", true},
+		{"https://teams.microsoft.com/meet/1234567890?p=secret", true},
+		{"https://TEAMS.Microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0", true},
+		{"https://teams.live.com/meet/9876543210?p=secret", true},
+		{"https://teams.live.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0", true},
+		{"https://gov.teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0", true},
+		{"https://teams.microsoft.com.evil.example/l/meetup-join/19%3ameeting_abc%40thread.v2/0", false},
+		{"https://teams.live.com.evil.example/meet/9876543210", false},
+		{"https://evilteams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0", false},
+		{"https://sub.teams.live.com.example/meet/9876543210", false},
+		{"https://example.com/teams.microsoft.com/l/meetup-join/", false},
+		{"https://microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0", false},
+		{"https://meet.google.com/abc-defg-hij", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isTeamsURL(u); got != tt.want {
+			t.Errorf("isTeamsURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
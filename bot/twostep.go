@@ -0,0 +1,269 @@
+package bot
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// Challenge is a verification step Google shows after the password.
+type Challenge string
+
+const (
+	ChallengeTOTP        Challenge = "totp"         // authenticator app code
+	ChallengePhonePrompt Challenge = "phone_prompt" // "Check your phone" tap prompt
+	ChallengeSMS         Challenge = "sms"          // code sent by text or call
+	ChallengeSecurityKey Challenge = "security_key"
+	ChallengeCaptcha     Challenge = "captcha"
+	ChallengeSelection   Challenge = "selection" // "Choose how you want to sign in"
+	ChallengeUnknown     Challenge = "unknown"
+)
+
+// ChallengeError is returned by GoogleLogin when Google asks for a
+// verification step the bot cannot complete on its own. Someone has to sign
+// in to the account by hand, or configure GOOGLE_TOTP_SECRET.
+type ChallengeError struct {
+	Challenge Challenge
+	URL       string
+}
+
+func (e *ChallengeError) Error() string {
+	return fmt.Sprintf("google login needs manual verification: %s challenge", e.Challenge)
+}
+
+// Unwrap lets errors.Is match ErrCaptcha or ErrTwoFactorRequired.
+func (e *ChallengeError) Unwrap() error {
+	if e.Challenge == ChallengeCaptcha {
+		return ErrCaptcha
+	}
+	return ErrTwoFactorRequired
+}
+
+// challengePaths maps the /challenge/<kind> segment of the sign-in URL to the
+// challenge it shows.
+var challengePaths = map[string]Challenge{
+	"totp":      ChallengeTOTP,
+	"az":        ChallengePhonePrompt,
+	"dp":        ChallengePhonePrompt,
+	"ipp":       ChallengeSMS,
+	"iap":       ChallengeSMS,
+	"sk":        ChallengeSecurityKey,
+	"recaptcha": ChallengeCaptcha,
+	"selection": ChallengeSelection,
+}
+
+var (
+	captchaSelectors = []string{
+		"img#captchaimg",
+		"iframe[title='reCAPTCHA']",
+		"iframe[src*='recaptcha']",
+	}
+	totpInputSelectors = []string{
+		"input[name='totpPin']",
+		"input#totpPin",
+		"input[type='tel'][autocomplete='one-time-code']",
+	}
+)
+
+const (
+	totpPeriod = 30
+	totpDigits = 6
+)
+
+// decodeTOTPSecret accepts the base32 secret as Google shows it: grouped
+// with spaces, in either case, with or without padding.
+func decodeTOTPSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	secret = strings.TrimRight(secret, "=")
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TOTP secret: %v", err)
+	}
+	if len(key) == 0 {
+		return nil, fmt.Errorf("invalid TOTP secret: empty")
+	}
+	return key, nil
+}
+
+// totpCode returns the RFC 6238 code for t: HMAC-SHA1 over the 30 second
+// step counter, dynamically truncated to six digits.
+func totpCode(key []byte, t time.Time) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(t.Unix())/totpPeriod)
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000)
+}
+
+// detectChallenge reports which verification step the page is showing, or
+// "" if none.
+func (b *Bot) detectChallenge() Challenge {
+	if b.isVisibleAny(captchaSelectors) {
+		return ChallengeCaptcha
+	}
+
+	if challenge := challengeFromURL(b.page.URL()); challenge != "" {
+		return challenge
+	}
+	if b.isVisibleAny(totpInputSelectors) {
+		return ChallengeTOTP
+	}
+	return ""
+}
+
+// challengeFromURL classifies a sign-in URL by its /challenge/<kind> path
+// segment, returning "" for URLs that are not a challenge page.
+func challengeFromURL(currentUrl string) Challenge {
+	i := strings.Index(currentUrl, "/challenge/")
+	if i < 0 {
+		return ""
+	}
+
+	kind := currentUrl[i+len("/challenge/"):]
+	if j := strings.IndexAny(kind, "/?#"); j >= 0 {
+		kind = kind[:j]
+	}
+	if challenge, ok := challengePaths[kind]; ok {
+		return challenge
+	}
+	return ChallengeUnknown
+}
+
+// waitForChallenge polls for up to timeout ms for a verification step to
+// appear after the password is submitted. It gives up early once Google
+// redirects away from the sign-in pages.
+func (b *Bot) waitForChallenge(ctx context.Context, timeout int) Challenge {
+	deadline := time.Now().Add(time.Duration(timeout) * time.Millisecond)
+	for {
+		if challenge := b.detectChallenge(); challenge != "" {
+			return challenge
+		}
+		if !strings.Contains(b.page.URL(), "accounts.google.com") || time.Now().After(deadline) {
+			return ""
+		}
+		if err := sleep(ctx, 250*time.Millisecond); err != nil {
+			return ""
+		}
+	}
+}
+
+// completeTwoStep passes 2-Step Verification with an authenticator code when
+// Google asks for one. Any other challenge, or a TOTP challenge without a
+// configured secret, is returned as a *ChallengeError.
+func (b *Bot) completeTwoStep(ctx context.Context) error {
+	challenge := b.waitForChallenge(ctx, 5000)
+	if challenge == "" {
+		return ctx.Err()
+	}
+	log.Printf("[TWO_STEP] Google is showing a %s challenge", challenge)
+
+	if challenge == ChallengeCaptcha || b.totpKey == nil {
+		return &ChallengeError{Challenge: challenge, URL: b.page.URL()}
+	}
+
+	// Google picks the account's default method; switch to the authenticator
+	if challenge != ChallengeTOTP {
+		if err := b.selectTOTPChallenge(ctx); err != nil {
+			log.Printf("[TWO_STEP] Could not switch to authenticator code: %v", err)
+			return &ChallengeError{Challenge: challenge, URL: b.page.URL()}
+		}
+	}
+
+	return b.enterTOTP(ctx)
+}
+
+// selectTOTPChallenge goes through "Try another way" to the list of
+// verification methods and picks the authenticator app.
+func (b *Bot) selectTOTPChallenge(ctx context.Context) error {
+	if b.detectChallenge() != ChallengeSelection {
+		anotherWaySelectors := []string{
+			"button:has-text('Try another way')",
+			"div[role='button']:has-text('Try another way')",
+		}
+		err := b.clickFirstVisible(ctx, anotherWaySelectors, 2000, "TRY_ANOTHER_WAY", "Google Login - 2-Step Verification")
+		if err != nil {
+			return fmt.Errorf("no way to pick another verification method")
+		}
+	}
+
+	authenticatorSelectors := []string{
+		"[data-challengetype='6']",
+		"div[role='link']:has-text('Google Authenticator')",
+		"li:has-text('Google Authenticator')",
+		"text=Get a verification code from the Google Authenticator app",
+	}
+	err := b.clickFirstVisible(ctx, authenticatorSelectors, 3000, "SELECT_AUTHENTICATOR", "Google Login - Choose Verification")
+	if err != nil {
+		return fmt.Errorf("authenticator app is not offered for this account")
+	}
+	return nil
+}
+
+// enterTOTP types the current code and submits it.
+func (b *Bot) enterTOTP(ctx context.Context) error {
+	codeInput, err := b.findElementFast(ctx, totpInputSelectors, 5000)
+	if err != nil {
+		return fmt.Errorf("failed to find verification code input")
+	}
+
+	// A code typed at the end of its window can expire before Google checks
+	// it, so wait for a fresh one
+	now := time.Now()
+	if remaining := totpPeriod - now.Unix()%totpPeriod; remaining < 3 {
+		if err := sleep(ctx, time.Duration(remaining)*time.Second); err != nil {
+			return err
+		}
+		now = time.Now()
+	}
+	code := totpCode(b.totpKey, now)
+
+	log.Printf("[TWO_STEP] Entering authenticator code")
+	err = b.page.Locator(codeInput).PressSequentially(code, playwright.LocatorPressSequentiallyOptions{
+		Delay: playwright.Float(50),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to type verification code: %v", err)
+	}
+
+	nextSelectors := []string{
+		"div#totpNext",
+		"button:has-text('Next')",
+		"div[role='button']:has-text('Next')",
+	}
+	err = b.clickFirstVisible(ctx, nextSelectors, 2000, "CLICK_TOTP_NEXT", "Google Login - 2-Step Verification")
+	if err != nil {
+		log.Printf("[KEYBOARD_ACTION] Pressing Enter key as fallback for verification code next")
+		if err := b.page.Keyboard().Press("Enter"); err != nil {
+			return fmt.Errorf("failed to submit verification code: %v", err)
+		}
+	}
+
+	wrongCodeSelectors := []string{
+		"text=Wrong code. Try again.",
+		"text=Wrong code",
+	}
+	if _, err := b.findElementFast(ctx, wrongCodeSelectors, 2000); err == nil {
+		return fmt.Errorf("%w: authenticator code was rejected, check GOOGLE_TOTP_SECRET and the system clock", ErrTwoFactorRequired)
+	}
+
+	// Google sometimes follows up with a different prompt
+	if challenge := b.detectChallenge(); challenge != "" && challenge != ChallengeTOTP {
+		return &ChallengeError{Challenge: challenge, URL: b.page.URL()}
+	}
+
+	fmt.Println("2-Step Verification code accepted")
+	return nil
+}
@@ -0,0 +1,114 @@
+package bot
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// rfc6238Key is the SHA-1 seed from RFC 6238 appendix B.
+var rfc6238Key = []byte("12345678901234567890")
+
+func TestTOTPCode(t *testing.T) {
+	// RFC 6238 appendix B, truncated from eight digits to six
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		if got := totpCode(rfc6238Key, time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("totpCode at %d = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+
+	// Codes hold for a whole 30 second step
+	if a, b := totpCode(rfc6238Key, time.Unix(60, 0)), totpCode(rfc6238Key, time.Unix(89, 0)); a != b {
+		t.Errorf("codes within one step differ: %s, %s", a, b)
+	}
+}
+
+func TestDecodeTOTPSecret(t *testing.T) {
+	// base32 of rfc6238Key
+	const secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+	valid := []string{
+		secret,
+		"gezd gnbv gy3t qojq gezd gnbv gy3t qojq", // as Google shows it
+		"GeZdGnBvGy3TqOjQgEzDgNbVgY3tQoJq",
+		secret + "====",
+	}
+	for _, s := range valid {
+		key, err := decodeTOTPSecret(s)
+		if err != nil || !bytes.Equal(key, rfc6238Key) {
+			t.Errorf("decodeTOTPSecret(%q) = %q, %v; want the RFC 6238 key", s, key, err)
+		}
+	}
+
+	// Unpadded secrets whose length isn't a multiple of eight
+	key, err := decodeTOTPSecret("JBSW Y3DP EHPK 3PXP")
+	if err != nil || string(key) != "Hello!\xde\xad\xbe\xef" {
+		t.Errorf("decodeTOTPSecret(JBSW Y3DP EHPK 3PXP) = %q, %v", key, err)
+	}
+	if key, err := decodeTOTPSecret("MZXW6"); err != nil || string(key) != "foo" {
+		t.Errorf("decodeTOTPSecret(MZXW6) = %q, %v", key, err)
+	}
+
+	invalid := []string{
+		"GEZDGNBV1Y3TQOJQ", // 1 is not in the base32 alphabet
+		"GEZDGNBV-GY3TQOJQ",
+		"not a secret!",
+		"    ",
+		"====",
+	}
+	for _, s := range invalid {
+		if key, err := decodeTOTPSecret(s); err == nil {
+			t.Errorf("decodeTOTPSecret(%q) = %q, want an error", s, key)
+		}
+	}
+}
+
+func TestChallengeFromURL(t *testing.T) {
+	const signin = "https://accounts.google.com/v3/signin"
+	tests := []struct {
+		url  string
+		want Challenge
+	}{
+		{signin + "/challenge/totp?TL=abc", ChallengeTOTP},
+		{signin + "/challenge/az?TL=abc", ChallengePhonePrompt},
+		{signin + "/challenge/dp", ChallengePhonePrompt},
+		{signin + "/challenge/ipp/collect?TL=abc", ChallengeSMS},
+		{signin + "/challenge/iap", ChallengeSMS},
+		{signin + "/challenge/sk/webauthn", ChallengeSecurityKey},
+		{signin + "/challenge/recaptcha#top", ChallengeCaptcha},
+		{signin + "/challenge/selection", ChallengeSelection},
+		{signin + "/challenge/pwd", ChallengeUnknown},
+		{signin + "/challenge/", ChallengeUnknown},
+		{signin + "/identifier", ""},
+		{"https://myaccount.google.com/", ""},
+		{"https://meet.google.com/abc-defg-hij", ""},
+	}
+	for _, tt := range tests {
+		if got := challengeFromURL(tt.url); got != tt.want {
+			t.Errorf("challengeFromURL(%s) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestChallengeError(t *testing.T) {
+	captcha := &ChallengeError{Challenge: ChallengeCaptcha}
+	if !errors.Is(captcha, ErrCaptcha) || errors.Is(captcha, ErrTwoFactorRequired) {
+		t.Errorf("captcha challenge should match ErrCaptcha only")
+	}
+	sms := &ChallengeError{Challenge: ChallengeSMS}
+	if !errors.Is(sms, ErrTwoFactorRequired) || errors.Is(sms, ErrCaptcha) {
+		t.Errorf("SMS challenge should match ErrTwoFactorRequired only")
+	}
+}
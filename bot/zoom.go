@@ -0,0 +1,357 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// zoom drives Zoom's "join from browser" web client as a guest.
+type zoom struct {
+	b *Bot
+}
+
+func newZoom(b *Bot) MeetingPlatform {
+	return &zoom{b: b}
+}
+
+func (z *zoom) Name() string {
+	return "zoom"
+}
+
+func isZoomURL(u *url.URL) bool {
+	host := strings.ToLower(u.Hostname())
+	return host == "zoom.us" || strings.HasSuffix(host, ".zoom.us") ||
+		host == "zoomgov.com" || strings.HasSuffix(host, ".zoomgov.com")
+}
+
+// Login is a no-op; the bot joins Zoom meetings as a guest.
+func (z *zoom) Login(ctx context.Context) error {
+	return nil
+}
+
+// zoomMeetingID finds the meeting number in the link formats Zoom hands out:
+// /j/<id>, /s/<id>, /wc/join/<id> and /wc/<id>/join.
+var zoomMeetingID = regexp.MustCompile(`^/(?:j|s|wc/join|wc)/(\d+)`)
+
+// zoomWebClientURL rewrites a meeting link to the web client, skipping the
+// launch page that tries to open the desktop app. The passcode in pwd is
+// carried over.
+func zoomWebClientURL(meetingURL string) (string, error) {
+	u, err := url.Parse(meetingURL)
+	if err != nil {
+		return "", err
+	}
+	m := zoomMeetingID.FindStringSubmatch(u.Path)
+	if m == nil {
+		return "", fmt.Errorf("no meeting number in %s", u.Path)
+	}
+
+	web := url.URL{
+		Scheme: "https",
+		Host:   u.Host,
+		Path:   "/wc/join/" + m[1],
+	}
+	if pwd := u.Query().Get("pwd"); pwd != "" {
+		web.RawQuery = url.Values{"pwd": {pwd}}.Encode()
+	}
+	return web.String(), nil
+}
+
+var (
+	// The waiting room has a Leave button of its own, so these are
+	// limited to the meeting footer
+	zoomLeaveSelectors = []string{
+		"button.footer__leave-btn",
+		"#foot-bar button[aria-label='Leave']",
+		"#foot-bar button:has-text('Leave')",
+	}
+	zoomWaitingRoomLeaveSelectors = []string{
+		".wr-container button:has-text('Leave')",
+		".wr-container button[aria-label='Leave']",
+	}
+	zoomMutedSelectors = []string{
+		"button[aria-label*='unmute my microphone']",
+		"button[aria-label='Unmute']",
+	}
+	zoomUnmutedSelectors = []string{
+		"button[aria-label*='mute my microphone']:not([aria-label*='unmute'])",
+		"button[aria-label='Mute']",
+	}
+	zoomWaitingRoomSelectors = []string{
+		"text=the meeting host will let you in soon",
+		"text=We've let them know you're here",
+		"text=Waiting for the host to start this meeting",
+		".wr-container",
+	}
+	zoomRejectedSelectors = []string{
+		"text=Passcode wrong",
+		"text=passcode is wrong",
+		"text=You have been removed from this meeting",
+		"text=The host has removed you",
+		"text=This meeting has been ended by host",
+	}
+)
+
+// classifyRejection tells a wrong passcode and an ended meeting, which
+// zoomRejectedSelectors also catch, apart from being removed by the host.
+func (z *zoom) classifyRejection(rejected string) error {
+	text := strings.ToLower(rejected)
+	switch {
+	case strings.Contains(text, "passcode"):
+		return ErrWrongPasscode
+	case strings.Contains(text, "ended"):
+		return ErrMeetingEnded
+	}
+	return nil
+}
+
+func (z *zoom) Join(ctx context.Context, meetingURL string, opts JoinOptions) error {
+	b := z.b
+
+	fmt.Printf("Joining Zoom meeting: %s\n", meetingURL)
+
+	_, err := b.page.Goto(meetingURL, playwright.PageGotoOptions{
+		WaitUntil: playwright.WaitUntilStateLoad,
+		Timeout:   pwTimeout(ctx, navigationTimeout),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to navigate to meeting URL: %v", err)
+	}
+
+	// The launch page only offers the browser client after trying the app
+	if !strings.Contains(b.page.URL(), "/wc/") {
+		launchSelectors := []string{
+			"div[role='button']:has-text('Launch Meeting')",
+			"button:has-text('Launch Meeting')",
+		}
+		b.clickFirstVisible(ctx, launchSelectors, 3000, "LAUNCH_MEETING", "Zoom - Launch Page")
+
+		browserSelectors := []string{
+			"a:has-text('Join from your browser')",
+			"a:has-text('Join from Your Browser')",
+		}
+		err = b.clickFirstVisible(ctx, browserSelectors, 3000, "JOIN_FROM_BROWSER", "Zoom - Launch Page")
+		if err != nil {
+			webURL, urlErr := zoomWebClientURL(meetingURL)
+			if urlErr != nil {
+				return fmt.Errorf("could not find the browser join link: %v", urlErr)
+			}
+			fmt.Printf("Browser join link not found, opening web client directly: %s\n", webURL)
+			_, err = b.page.Goto(webURL, playwright.PageGotoOptions{
+				WaitUntil: playwright.WaitUntilStateLoad,
+				Timeout:   pwTimeout(ctx, navigationTimeout),
+			})
+			if err != nil {
+				return fmt.Errorf("failed to navigate to web client: %v", err)
+			}
+		}
+	}
+
+	// The newer web client renders inside an iframe; load it top level so
+	// the usual page selectors reach it
+	if src, err := b.page.Locator("iframe#webclient").GetAttribute("src", playwright.LocatorGetAttributeOptions{
+		Timeout: pwTimeout(ctx, 3000),
+	}); err == nil && src != "" {
+		if base, err := url.Parse(b.page.URL()); err == nil {
+			if frameURL, err := base.Parse(src); err == nil {
+				fmt.Println("Opening Zoom web client frame directly...")
+				b.page.Goto(frameURL.String(), playwright.PageGotoOptions{
+					WaitUntil: playwright.WaitUntilStateLoad,
+					Timeout:   pwTimeout(ctx, navigationTimeout),
+				})
+			}
+		}
+	}
+
+	if err := b.joinStep(ctx, opts, JoinPhasePreJoin); err != nil {
+		return err
+	}
+
+	b.ClearPopups(ctx)
+
+	nameSelectors := []string{
+		"input#input-for-name",
+		"input#inputname",
+		"input[placeholder='Your Name']",
+	}
+	nameSelector, err := b.findElementFast(ctx, nameSelectors, 15000)
+	if err != nil {
+		return fmt.Errorf("failed to find the name field")
+	}
+	if opts.DisplayName != "" {
+		err = b.page.Locator(nameSelector).Fill(opts.DisplayName)
+		if err != nil {
+			return fmt.Errorf("failed to enter display name: %v", err)
+		}
+	}
+
+	// Only shown when the link did not carry the passcode
+	passcodeSelectors := []string{
+		"input#input-for-pwd",
+		"input#inputpasscode",
+		"input[type='password']",
+	}
+	if selector, err := b.findElementFast(ctx, passcodeSelectors, 1000); err == nil {
+		if opts.Password == "" {
+			return fmt.Errorf("meeting requires a passcode and none was given")
+		}
+		err = b.page.Locator(selector).Fill(opts.Password)
+		if err != nil {
+			return fmt.Errorf("failed to enter passcode: %v", err)
+		}
+	}
+
+	// Preview toggles: camera off before joining
+	previewCameraSelectors := []string{
+		"button#preview-video-control-button[aria-label*='Stop Video']",
+		"button[aria-label='Stop Video']",
+	}
+	if selector, err := b.findElementFast(ctx, previewCameraSelectors, 1000); err == nil {
+		b.clickWithLogging(ctx, selector, "TOGGLE_CAMERA_OFF", "Zoom - Pre-join Setup")
+	}
+
+	if err := b.joinStep(ctx, opts, JoinPhaseAskingToJoin); err != nil {
+		return err
+	}
+
+	joinSelectors := []string{
+		"button.preview-join-button",
+		"button#joinBtn",
+		"button:has-text('Join')",
+	}
+	err = b.clickFirstVisible(ctx, joinSelectors, 3000, "JOIN_MEETING", "Zoom - Join Meeting")
+	if err != nil {
+		return fmt.Errorf("could not find join button")
+	}
+
+	if err := b.setState(StateWaitingInLobby); err != nil {
+		return err
+	}
+
+	fmt.Println("Waiting for Zoom meeting to load...")
+
+	if rejected := b.visibleText(zoomRejectedSelectors); rejected != "" {
+		return rejectionError(z, rejected)
+	}
+
+	if _, err := b.findElementFast(ctx, zoomLeaveSelectors, 8000); err != nil || b.isVisibleAny(zoomWaitingRoomSelectors) {
+		if b.isVisibleAny(zoomWaitingRoomSelectors) {
+			fmt.Println("Waiting in the Zoom waiting room for the host to admit us...")
+		} else {
+			fmt.Println("Zoom meeting UI not detected yet, still waiting for admission...")
+		}
+		return nil
+	}
+
+	fmt.Println("Successfully joined the Zoom meeting!")
+	return b.admitted(ctx)
+}
+
+// onAdmitted connects computer audio, without which the meeting hears nothing
+// from the virtual mic. Zoom only offers it once we are in the meeting.
+func (z *zoom) onAdmitted(ctx context.Context) {
+	audioSelectors := []string{
+		"button.join-audio-by-voip__join-btn",
+		"button:has-text('Join Audio by Computer')",
+		"button:has-text('Join with Computer Audio')",
+		"button:has-text('Join Audio')",
+	}
+	err := z.b.clickFirstVisible(ctx, audioSelectors, 5000, "JOIN_AUDIO", "Zoom - Audio")
+	if err != nil {
+		fmt.Println("Join audio prompt not found, computer audio may already be connected")
+	}
+}
+
+func (z *zoom) Leave(ctx context.Context) error {
+	b := z.b
+
+	fmt.Println("Attempting to leave the Zoom meeting...")
+
+	err := b.clickFirstVisible(ctx, zoomLeaveSelectors, 3000, "LEAVE_MEETING", "Zoom - Meeting Controls")
+	if err != nil {
+		err = b.clickFirstVisible(ctx, zoomWaitingRoomLeaveSelectors, 1000, "LEAVE_WAITING_ROOM", "Zoom - Waiting Room")
+	}
+	if err != nil {
+		return fmt.Errorf("could not find leave button")
+	}
+
+	// Leave opens a menu offering "Leave Meeting" (and "End" for hosts)
+	confirmSelectors := []string{
+		"button.leave-meeting-options__btn",
+		"button:has-text('Leave Meeting')",
+	}
+	b.clickFirstVisible(ctx, confirmSelectors, 2000, "CONFIRM_LEAVE", "Zoom - Leave Menu")
+
+	if _, err := b.findElementFast(ctx, zoomLeaveSelectors, 1000); err == nil {
+		fmt.Println("Zoom meeting exit status unclear, but leave command was executed")
+		return nil
+	}
+
+	fmt.Println("Successfully left the Zoom meeting!")
+	return nil
+}
+
+func (z *zoom) SetMic(ctx context.Context, on bool) error {
+	if on {
+		err := z.b.clickFirstVisible(ctx, zoomMutedSelectors, 2000, "ENABLE_MICROPHONE", "Zoom - Meeting Controls")
+		if err != nil {
+			return fmt.Errorf("could not find microphone enable button")
+		}
+		return nil
+	}
+
+	err := z.b.clickFirstVisible(ctx, zoomUnmutedSelectors, 2000, "DISABLE_MICROPHONE", "Zoom - Meeting Controls")
+	if err != nil {
+		return fmt.Errorf("could not find microphone disable button")
+	}
+	return nil
+}
+
+func (z *zoom) SetCamera(ctx context.Context, on bool) error {
+	if on {
+		cameraSelectors := []string{
+			"button[aria-label*='start sending my video']",
+			"button[aria-label='Start Video']",
+		}
+		err := z.b.clickFirstVisible(ctx, cameraSelectors, 2000, "ENABLE_CAMERA", "Zoom - Meeting Controls")
+		if err != nil {
+			return fmt.Errorf("could not find camera enable button")
+		}
+		return nil
+	}
+
+	cameraSelectors := []string{
+		"button[aria-label*='stop sending my video']",
+		"button[aria-label='Stop Video']",
+	}
+	err := z.b.clickFirstVisible(ctx, cameraSelectors, 2000, "DISABLE_CAMERA", "Zoom - Meeting Controls")
+	if err != nil {
+		return fmt.Errorf("could not find camera disable button")
+	}
+	return nil
+}
+
+func (z *zoom) DetectState(ctx context.Context) (*MeetingStatus, error) {
+	b := z.b
+
+	status := &MeetingStatus{
+		URL:       b.page.URL(),
+		InMeeting: b.isVisibleAny(zoomLeaveSelectors) && !b.isVisibleAny(zoomWaitingRoomSelectors),
+		Rejected:  b.visibleText(zoomRejectedSelectors),
+	}
+
+	if b.isVisibleAny(zoomMutedSelectors) {
+		muted := true
+		status.MicrophoneMuted = &muted
+	} else if b.isVisibleAny(zoomUnmutedSelectors) {
+		muted := false
+		status.MicrophoneMuted = &muted
+	}
+
+	return status, nil
+}
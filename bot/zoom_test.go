@@ -0,0 +1,87 @@
+package bot
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+)
+
+func TestIsZoomURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://zoom.us/j/1234567890", true},
+		{"https://us02web.zoom.us/j/1234567890?pwd=abc", true},
+		{"https://acme.zoom.us/my/standup", true},
+		{"https://ZOOM.US/j/1234567890", true},
+		{"https://zoomgov.com/j/1234567890", true},
+		{"https://agency.zoomgov.com/j/1234567890", true},
+		{"https://zoom.us:443/j/1234567890", true},
+		{"https://zoom.us.evil.example/j/1234567890", false},
+		{"https://evilzoom.us/j/1234567890", false},
+		{"https://example.com/zoom.us/j/1234567890", false},
+		{"https://meet.google.com/abc-defg-hij", false},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := isZoomURL(u); got != tt.want {
+			t.Errorf("isZoomURL(%q) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestZoomWebClientURL(t *testing.T) {
+	tests := []struct {
+		url, want string
+	}{
+		{"https://zoom.us/j/1234567890", "https://zoom.us/wc/join/1234567890"},
+		{"https://us02web.zoom.us/j/1234567890?pwd=aBc.1", "https://us02web.zoom.us/wc/join/1234567890?pwd=aBc.1"},
+		// Only the passcode is carried over
+		{"https://acme.zoom.us/j/1234567890?pwd=secret&uname=Bot&from=addon", "https://acme.zoom.us/wc/join/1234567890?pwd=secret"},
+		{"https://zoom.us/s/1234567890", "https://zoom.us/wc/join/1234567890"},
+		{"https://zoom.us/wc/join/1234567890", "https://zoom.us/wc/join/1234567890"},
+		{"https://zoom.us/wc/1234567890/join?pwd=x", "https://zoom.us/wc/join/1234567890?pwd=x"},
+		{"http://zoom.us/j/1234567890", "https://zoom.us/wc/join/1234567890"},
+		{"https://zoomgov.com/j/1234567890", "https://zoomgov.com/wc/join/1234567890"},
+	}
+	for _, tt := range tests {
+		got, err := zoomWebClientURL(tt.url)
+		if err != nil || got != tt.want {
+			t.Errorf("zoomWebClientURL(%q) = %q, %v, want %q", tt.url, got, err, tt.want)
+		}
+	}
+
+	// Vanity links name a room rather than a meeting number
+	for _, bad := range []string{"https://acme.zoom.us/my/standup", "https://zoom.us/", "https://zoom.us/j/standup"} {
+		if got, err := zoomWebClientURL(bad); err == nil {
+			t.Errorf("zoomWebClientURL(%q) = %q, want an error", bad, got)
+		}
+	}
+}
+
+func TestZoomRejectionError(t *testing.T) {
+	z := &zoom{}
+	tests := []struct {
+		rejected string
+		want     error
+	}{
+		{"Passcode wrong", ErrWrongPasscode},
+		{"The meeting passcode is wrong. Please try again.", ErrWrongPasscode},
+		{"This meeting has been ended by host", ErrMeetingEnded},
+		{"You have been removed from this meeting", ErrAdmissionDenied},
+		{"The host has removed you", ErrAdmissionDenied},
+	}
+	for _, tt := range tests {
+		err := rejectionError(z, tt.rejected)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("rejectionError(%q) = %v, want %v", tt.rejected, err, tt.want)
+		}
+		if tt.want != ErrAdmissionDenied && errors.Is(err, ErrAdmissionDenied) {
+			t.Errorf("rejectionError(%q) = %v, want it not to count as a denial", tt.rejected, err)
+		}
+	}
+}
@@ -30,6 +30,7 @@ docker run -d \
   --name meetbot-go-container \
   -p 8080:8080 \
   --shm-size=2gb \
+  --stop-timeout 35 \
   -e DISPLAY=:99 \
   -e PLAYWRIGHT_BROWSERS_PATH=/ms-playwright \
   meetbot-go
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"meetbot-go-2/bot"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// events carries activity from every session to /events subscribers.
+var events = bot.NewEventBus()
+
+// sseKeepAlive is how often an idle event stream sends a comment line so
+// proxies do not time the connection out.
+const sseKeepAlive = 15 * time.Second
+
+// eventMatches applies the optional ?session= filter of the event endpoints.
+func eventMatches(r *http.Request, e bot.Event) bool {
+	session := r.URL.Query().Get("session")
+	return session == "" || e.Source == session
+}
+
+// eventsHandler streams bot events as Server-Sent Events. Clients that
+// reconnect with Last-Event-ID first receive the retained events they missed.
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sub := events.Subscribe(64)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	writeEvent := func(e bot.Event) error {
+		if !eventMatches(r, e) {
+			return nil
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+		return err
+	}
+
+	// Subscribing before replaying means an event can show up in both; the
+	// ID check skips the duplicate.
+	var lastID uint64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if id, err := strconv.ParseUint(v, 10, 64); err == nil {
+			for _, e := range events.Since(id) {
+				if writeEvent(e) != nil {
+					return
+				}
+				lastID = e.ID
+			}
+		}
+	}
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-shuttingDown:
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if e.ID <= lastID {
+				continue
+			}
+			if writeEvent(e) != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// eventsWebSocketHandler streams bot events as JSON text messages over a
+// WebSocket. Anything the client sends other than control frames is ignored.
+func eventsWebSocketHandler(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if errors.Is(err, errWebSocketOrigin) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	sub := events.Subscribe(64)
+	defer sub.Close()
+
+	// The read loop handles pings and notices when the client goes away
+	closed := make(chan struct{})
+	go func() {
+		conn.readLoop()
+		close(closed)
+	}()
+
+	ticker := time.NewTicker(sseKeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-shuttingDown:
+			conn.writeFrame(wsOpClose, nil)
+			return
+		case <-ticker.C:
+			if err := conn.writeFrame(wsOpPing, nil); err != nil {
+				return
+			}
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if !eventMatches(r, e) {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			if err := conn.writeFrame(wsOpText, data); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// publishEvent reports activity that happens outside bot.Bot, such as TTS
+// playback, on behalf of a session.
+func publishEvent(t bot.EventType, sessionID string, data map[string]any) {
+	events.Publish(bot.Event{
+		Type:   t,
+		Source: sessionID,
+		Data:   data,
+	})
+}
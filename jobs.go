@@ -0,0 +1,320 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"meetbot-go-2/bot"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// jobHistorySize bounds how many finished jobs are kept for GET /jobs/{id}.
+const jobHistorySize = 200
+
+var (
+	ErrJobNotFound = errors.New("job not found")
+	ErrJobFinished = errors.New("job already finished")
+	ErrJobActive   = errors.New("a join is already in progress for this session")
+)
+
+type JobStatus string
+
+const (
+	JobPending   JobStatus = "pending"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	JobFailed    JobStatus = "failed"
+	JobCanceled  JobStatus = "canceled"
+)
+
+// joinProgress maps join phases to a rough completion percentage.
+var joinProgress = map[bot.JoinPhase]int{
+	bot.JoinPhaseLoggingIn:           10,
+	bot.JoinPhaseNavigating:          30,
+	bot.JoinPhasePreJoin:             45,
+	bot.JoinPhaseAskingToJoin:        60,
+	bot.JoinPhaseWaitingForAdmission: 75,
+	bot.JoinPhaseJoined:              100,
+}
+
+// Job is a join running in the background. Fields are guarded by the
+// manager's lock; handlers only see copies.
+type Job struct {
+	ID         string     `json:"id"`
+	Session    string     `json:"session"`
+	URL        string     `json:"url"`
+	Status     JobStatus  `json:"status"`
+	Phase      string     `json:"phase"`
+	Progress   int        `json:"progress"`
+	Outcome    string     `json:"outcome,omitempty"` // joined, denied, admission_timeout, canceled or failed
+	Error      string     `json:"error,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+	UpdatedAt  time.Time  `json:"updatedAt"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+
+	cancel   context.CancelFunc
+	finished chan struct{} // closed once the job is done
+}
+
+func (j *Job) done() bool {
+	return j.Status == JobSucceeded || j.Status == JobFailed || j.Status == JobCanceled
+}
+
+// JobManager runs join jobs and remembers recent ones.
+type JobManager struct {
+	mu    sync.Mutex
+	jobs  map[string]*Job
+	order []*Job
+
+	// join performs a job's join. Tests replace it, having no browser.
+	join func(ctx context.Context, job *Job, opts bot.JoinOptions) error
+}
+
+func NewJobManager() *JobManager {
+	m := &JobManager{jobs: make(map[string]*Job)}
+	m.join = m.runJoin
+	return m
+}
+
+// StartJoin queues a join of meetingURL on the session and returns at once.
+// Only one join may run per session.
+func (m *JobManager) StartJoin(sessionID, meetingURL string, opts bot.JoinOptions) (Job, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+	job := &Job{
+		ID:        newID(),
+		Session:   sessionID,
+		URL:       meetingURL,
+		Status:    JobPending,
+		Phase:     "queued",
+		CreatedAt: now,
+		UpdatedAt: now,
+		cancel:    cancel,
+		finished:  make(chan struct{}),
+	}
+
+	m.mu.Lock()
+	for _, other := range m.order {
+		if other.Session == sessionID && !other.done() {
+			m.mu.Unlock()
+			cancel()
+			return Job{}, ErrJobActive
+		}
+	}
+	m.jobs[job.ID] = job
+	m.order = append(m.order, job)
+	m.evictLocked()
+	snapshot := *job
+	m.mu.Unlock()
+
+	opts.Progress = func(phase bot.JoinPhase) {
+		m.update(job, func(j *Job) {
+			j.Phase = string(phase)
+			j.Progress = joinProgress[phase]
+		})
+	}
+
+	go func() {
+		defer cancel()
+		err := m.join(ctx, job, opts)
+		m.finish(job, err)
+	}()
+
+	log.Printf("[JOBS] Started join job %s for session %s", job.ID, sessionID)
+	return snapshot, nil
+}
+
+func (m *JobManager) runJoin(ctx context.Context, job *Job, opts bot.JoinOptions) error {
+	m.update(job, func(j *Job) {
+		j.Status = JobRunning
+		j.Phase = "starting"
+	})
+
+	// The default session is started on demand; named sessions must exist
+	var session *Session
+	var err error
+	if job.Session == defaultSessionID {
+		session, err = sessions.GetOrCreate(ctx, job.Session)
+	} else {
+		session, err = sessions.Get(job.Session)
+	}
+	if err != nil {
+		return err
+	}
+
+	// The lock is released while the bot waits in a lobby, so status,
+	// screenshots and leave requests are not held up for the whole wait
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	opts.PageLock = &session.mu
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return session.Bot.Join(ctx, job.URL, opts)
+}
+
+func (m *JobManager) finish(job *Job, err error) {
+	status, outcome := JobSucceeded, "joined"
+	switch {
+	case err == nil:
+	case errors.Is(err, context.Canceled):
+		status, outcome = JobCanceled, "canceled"
+	case errors.Is(err, bot.ErrAdmissionDenied):
+		status, outcome = JobFailed, "denied"
+	case errors.Is(err, bot.ErrAdmissionTimeout):
+		status, outcome = JobFailed, "admission_timeout"
+	case errors.Is(err, bot.ErrWrongPasscode):
+		status, outcome = JobFailed, "wrong_passcode"
+	case errors.Is(err, bot.ErrMeetingEnded):
+		status, outcome = JobFailed, "meeting_ended"
+	default:
+		status, outcome = JobFailed, "failed"
+	}
+
+	m.update(job, func(j *Job) {
+		now := time.Now()
+		j.Status = status
+		j.Outcome = outcome
+		j.FinishedAt = &now
+		if err != nil {
+			j.Error = err.Error()
+		} else {
+			j.Progress = 100
+		}
+	})
+
+	close(job.finished)
+
+	if err != nil {
+		log.Printf("[JOBS] Join job %s %s: %v", job.ID, outcome, err)
+	}
+}
+
+func (m *JobManager) update(job *Job, fn func(j *Job)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	fn(job)
+	job.UpdatedAt = time.Now()
+}
+
+// evictLocked drops the oldest finished jobs beyond jobHistorySize.
+func (m *JobManager) evictLocked() {
+	kept := m.order[:0]
+	excess := len(m.order) - jobHistorySize
+	for _, job := range m.order {
+		if excess > 0 && job.done() {
+			delete(m.jobs, job.ID)
+			excess--
+			continue
+		}
+		kept = append(kept, job)
+	}
+	m.order = kept
+}
+
+func (m *JobManager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return Job{}, ErrJobNotFound
+	}
+	return *job, nil
+}
+
+// Cancel stops a pending or running job. The job reports canceled once the
+// bot has stopped and withdrawn from the meeting.
+func (m *JobManager) Cancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, ok := m.jobs[id]
+	if !ok {
+		return ErrJobNotFound
+	}
+	if job.done() {
+		return ErrJobFinished
+	}
+	job.cancel()
+	return nil
+}
+
+// CancelSession stops any join running on the session, e.g. before the bot
+// leaves or the session is closed, and waits until it has stopped or ctx
+// ends. It reports whether there was a join to stop.
+func (m *JobManager) CancelSession(ctx context.Context, sessionID string) bool {
+	return m.cancelWhere(ctx, func(job *Job) bool { return job.Session == sessionID })
+}
+
+// CancelAll stops every pending or running join, e.g. on shutdown, and waits
+// until they have stopped or ctx ends.
+func (m *JobManager) CancelAll(ctx context.Context) {
+	m.cancelWhere(ctx, func(job *Job) bool { return true })
+}
+
+// cancelWhere cancels the unfinished jobs that match and waits for them as
+// CancelSession does.
+func (m *JobManager) cancelWhere(ctx context.Context, match func(job *Job) bool) bool {
+	m.mu.Lock()
+	var running []*Job
+	for _, job := range m.order {
+		if match(job) && !job.done() {
+			job.cancel()
+			running = append(running, job)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, job := range running {
+		select {
+		case <-job.finished:
+		case <-ctx.Done():
+			return true
+		}
+	}
+	return len(running) > 0
+}
+
+var jobs = NewJobManager()
+
+func jobHandler(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+
+	switch r.Method {
+	case http.MethodGet:
+		job, err := jobs.Get(id)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(job)
+
+	case http.MethodDelete:
+		fmt.Printf("Processing cancel request for job %s...\n", id)
+
+		err := jobs.Cancel(id)
+		switch {
+		case errors.Is(err, ErrJobNotFound):
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		case errors.Is(err, ErrJobFinished):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("Job cancellation requested"))
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"meetbot-go-2/bot"
+)
+
+// stubJoin stands in for joining a meeting. Each join reports the navigating
+// phase, then waits for the test to send its result on finish or for the job
+// to be cancelled.
+type stubJoin struct {
+	started chan string // job IDs
+	finish  chan error
+}
+
+func newStubJobs() (*JobManager, *stubJoin) {
+	m := NewJobManager()
+	s := &stubJoin{started: make(chan string, jobHistorySize+16), finish: make(chan error)}
+	m.join = func(ctx context.Context, job *Job, opts bot.JoinOptions) error {
+		m.update(job, func(j *Job) { j.Status = JobRunning })
+		opts.Progress(bot.JoinPhaseNavigating)
+		s.started <- job.ID
+		select {
+		case err := <-s.finish:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return m, s
+}
+
+func startJob(t *testing.T, m *JobManager, sessionID string) Job {
+	t.Helper()
+	job, err := m.StartJoin(sessionID, "https://meet.jit.si/Standup", bot.JoinOptions{})
+	if err != nil {
+		t.Fatalf("StartJoin(%s): %v", sessionID, err)
+	}
+	return job
+}
+
+func waitJob(t *testing.T, m *JobManager, job Job) Job {
+	t.Helper()
+	select {
+	case <-job.finished:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job %s did not finish", job.ID)
+	}
+	got, err := m.Get(job.ID)
+	if err != nil {
+		t.Fatalf("Get(%s): %v", job.ID, err)
+	}
+	return got
+}
+
+func TestJobLifecycle(t *testing.T) {
+	m, s := newStubJobs()
+
+	job := startJob(t, m, "default")
+	if job.Status != JobPending || job.Phase != "queued" || job.Session != "default" {
+		t.Errorf("StartJoin() = %+v, want a queued job for the session", job)
+	}
+
+	<-s.started
+	running, _ := m.Get(job.ID)
+	if running.Status != JobRunning || running.Phase != string(bot.JoinPhaseNavigating) || running.Progress != joinProgress[bot.JoinPhaseNavigating] {
+		t.Errorf("running job = %+v, want it to report the navigating phase", running)
+	}
+
+	s.finish <- nil
+	done := waitJob(t, m, job)
+	if done.Status != JobSucceeded || done.Outcome != "joined" || done.Progress != 100 || done.FinishedAt == nil || done.Error != "" {
+		t.Errorf("finished job = %+v, want succeeded and joined", done)
+	}
+	if err := m.Cancel(job.ID); !errors.Is(err, ErrJobFinished) {
+		t.Errorf("Cancel() after finishing = %v, want %v", err, ErrJobFinished)
+	}
+	if _, err := m.Get("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Get(missing) = %v, want %v", err, ErrJobNotFound)
+	}
+}
+
+func TestJobOutcome(t *testing.T) {
+	tests := []struct {
+		err     error
+		outcome string
+	}{
+		{fmt.Errorf("%w: Someone in the call denied your request", bot.ErrAdmissionDenied), "denied"},
+		{fmt.Errorf("%w after 5m0s", bot.ErrAdmissionTimeout), "admission_timeout"},
+		{fmt.Errorf("%w: Passcode wrong", bot.ErrWrongPasscode), "wrong_passcode"},
+		{fmt.Errorf("%w: This meeting has been ended by host", bot.ErrMeetingEnded), "meeting_ended"},
+		{errors.New("could not find join button"), "failed"},
+	}
+	for _, tt := range tests {
+		m, s := newStubJobs()
+		job := startJob(t, m, "default")
+		<-s.started
+		s.finish <- tt.err
+
+		done := waitJob(t, m, job)
+		if done.Status != JobFailed || done.Outcome != tt.outcome || done.Error != tt.err.Error() {
+			t.Errorf("job failing with %q = %+v, want outcome %s", tt.err, done, tt.outcome)
+		}
+	}
+}
+
+func TestJobActive(t *testing.T) {
+	m, s := newStubJobs()
+
+	first := startJob(t, m, "default")
+	if _, err := m.StartJoin("default", "https://meet.jit.si/Other", bot.JoinOptions{}); !errors.Is(err, ErrJobActive) {
+		t.Errorf("second StartJoin() = %v, want %v", err, ErrJobActive)
+	}
+	// Other sessions are not held up
+	other := startJob(t, m, "other")
+
+	<-s.started
+	<-s.started
+	s.finish <- nil
+	s.finish <- nil
+	waitJob(t, m, first)
+	waitJob(t, m, other)
+
+	again := startJob(t, m, "default")
+	<-s.started
+	s.finish <- nil
+	waitJob(t, m, again)
+}
+
+func TestJobCancel(t *testing.T) {
+	m, s := newStubJobs()
+
+	job := startJob(t, m, "default")
+	<-s.started
+	if err := m.Cancel(job.ID); err != nil {
+		t.Fatalf("Cancel() = %v", err)
+	}
+	done := waitJob(t, m, job)
+	if done.Status != JobCanceled || done.Outcome != "canceled" {
+		t.Errorf("cancelled job = %+v, want canceled", done)
+	}
+	if err := m.Cancel("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Cancel(missing) = %v, want %v", err, ErrJobNotFound)
+	}
+
+	// CancelSession waits for the join to stop
+	job = startJob(t, m, "default")
+	<-s.started
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if !m.CancelSession(ctx, "default") {
+		t.Error("CancelSession() = false, want true with a join running")
+	}
+	if got, _ := m.Get(job.ID); got.Status != JobCanceled {
+		t.Errorf("job after CancelSession = %+v, want canceled", got)
+	}
+	if m.CancelSession(ctx, "default") {
+		t.Error("CancelSession() = true with nothing running")
+	}
+}
+
+func TestJobEviction(t *testing.T) {
+	m := NewJobManager()
+	// Joins finish at once, except on the session kept running
+	m.join = func(ctx context.Context, job *Job, opts bot.JoinOptions) error {
+		if job.Session == "running" {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		return nil
+	}
+
+	// A job still running is kept however old it is
+	running := startJob(t, m, "running")
+
+	var finished []Job
+	for i := 0; i < jobHistorySize+5; i++ {
+		job := startJob(t, m, fmt.Sprintf("session-%d", i))
+		finished = append(finished, waitJob(t, m, job))
+	}
+	// Eviction happens as jobs are added
+	waitJob(t, m, startJob(t, m, "last"))
+
+	if _, err := m.Get(running.ID); err != nil {
+		t.Errorf("running job was evicted: %v", err)
+	}
+	evicted := 0
+	for _, job := range finished {
+		if _, err := m.Get(job.ID); errors.Is(err, ErrJobNotFound) {
+			evicted++
+		}
+	}
+	// The history holds jobHistorySize jobs, counting the running one and
+	// the last one added
+	if want := len(finished) + 2 - jobHistorySize; evicted != want {
+		t.Errorf("evicted %d finished jobs, want the oldest %d", evicted, want)
+	}
+	if _, err := m.Get(finished[0].ID); !errors.Is(err, ErrJobNotFound) {
+		t.Error("the oldest finished job was kept")
+	}
+	if _, err := m.Get(finished[len(finished)-1].ID); err != nil {
+		t.Errorf("the newest finished job was evicted: %v", err)
+	}
+
+	m.CancelAll(context.Background())
+	if got, _ := m.Get(running.ID); got.Status != JobCanceled {
+		t.Errorf("running job after CancelAll = %+v, want canceled", got)
+	}
+}
+
+func TestJobCancelAll(t *testing.T) {
+	m, s := newStubJobs()
+
+	first := startJob(t, m, "default")
+	second := startJob(t, m, "other")
+	<-s.started
+	<-s.started
+
+	// CancelAll returns once the joins have stopped
+	m.CancelAll(context.Background())
+	for _, job := range []Job{first, second} {
+		if got, _ := m.Get(job.ID); got.Status != JobCanceled {
+			t.Errorf("job %s after CancelAll = %+v, want canceled", job.Session, got)
+		}
+	}
+
+	// A join slow to stop holds CancelAll up only until ctx ends
+	release := make(chan struct{})
+	m.join = func(ctx context.Context, job *Job, opts bot.JoinOptions) error {
+		<-ctx.Done()
+		<-release
+		return ctx.Err()
+	}
+	slow := startJob(t, m, "default")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	m.CancelAll(ctx)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("CancelAll() took %v, want it bounded by ctx", elapsed)
+	}
+	close(release)
+	waitJob(t, m, slow)
+}
@@ -1,113 +1,71 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"io"
 	"log"
+	"meetbot-go-2/audio"
 	"meetbot-go-2/bot"
+	"meetbot-go-2/tts"
 	"net/http"
 	"os"
-	"os/exec"
-	"sync"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
-
-	"golang.org/x/sys/unix"
 )
 
-var (
-	globalBot *bot.Bot
-	botMutex  sync.Mutex
-)
+var sessions *SessionManager
 
-func openPipeNonBlocking(path string) (*os.File, error) {
-	fd, err := unix.Open(path, unix.O_WRONLY|unix.O_NONBLOCK, 0644)
-	if err != nil {
-		return nil, err
-	}
-	return os.NewFile(uintptr(fd), path), nil
-}
-
-func generateAndSendTTS(text string) error {
-	wavFile := "tts.wav"
-	pipePath := "/tmp/virtmic"
+// ttsEngines holds the configured text-to-speech engines.
+var ttsEngines *tts.Registry
 
-	// Generate WAV file using espeak-ng
-	convertedFile := "tts_48k_stereo.wav"
-	cmd := exec.Command("bash", "-c", fmt.Sprintf(
-		`espeak-ng -s 65 "%s" --stdout | sox -t wav - -r 48000 -c 2 -b 16 %s`, text, convertedFile,
-	))
-	err := cmd.Run()
+// generateAndSendTTS speaks text, or doc when it is not nil, through the
+// virtual mic and returns once it has finished playing.
+func generateAndSendTTS(ctx context.Context, text string, doc *tts.SSML, opts SpeechOptions, mic *virtualMic) (audio.Playback, error) {
+	engine, err := ttsEngines.Get(opts.Engine)
 	if err != nil {
-		return fmt.Errorf("failed to generate and convert wav: %v", err)
+		return audio.Playback{}, err
 	}
 
-	fmt.Println("Generated Wav file:", wavFile)
-
-	wav, err := os.Open(convertedFile)
-	if err != nil {
-		return fmt.Errorf("failed to open wav file: %v", err)
+	var stream *tts.Stream
+	if doc != nil {
+		stream, err = tts.SynthesizeSSML(ctx, engine, doc, opts.Voice, opts.Rate)
+	} else {
+		stream, err = engine.Synthesize(ctx, text, opts.Voice, opts.Rate)
 	}
-	defer wav.Close()
-
-	// Skip WAV header (44 bytes)
-	_, err = wav.Seek(44, io.SeekStart)
 	if err != nil {
-		return fmt.Errorf("failed to seek wav file: %v", err)
+		return audio.Playback{}, fmt.Errorf("failed to synthesize speech: %w", err)
 	}
 
-	if _, err := os.Stat(pipePath); os.IsNotExist(err) {
-		return fmt.Errorf("pipe does not exist: %s", pipePath)
-	}
+	fmt.Printf("Synthesizing speech with %s (%s)\n", engine.Name(), stream.Format)
 
-	fmt.Println("About to open pipe for writing...")
-
-	pipe, err := openPipeNonBlocking(pipePath)
+	pcm, err := audio.Convert(stream, stream.Format, micFormat)
 	if err != nil {
-		if err == unix.ENXIO {
-			// No reader on the other end of the pipe
-			return fmt.Errorf("no reader available on the pipe")
-		}
-		return fmt.Errorf("failed to open pipe: %v", err)
+		stream.Close()
+		return audio.Playback{}, fmt.Errorf("failed to convert speech: %w", err)
 	}
 
-	defer pipe.Close()
-
-	fmt.Println("Pipe opened successfully!")
-	fmt.Println("Sending audio data in real-time chunks...")
-
-	// Send audio in small chunks with timing to simulate real-time playback
-	buf := make([]byte, 8192) // Small chunks
-	for {
-		n, err := wav.Read(buf)
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			return fmt.Errorf("failed to read wav file: %v", err)
-		}
-
-		_, err = pipe.Write(buf[:n])
-		if err != nil {
-			return fmt.Errorf("failed to write to pipe: %v", err)
-		}
+	// The mixer closes the stream once it has read it, so the engine is not
+	// torn down under a read still in progress
+	playback, err := mic.Play(ctx, struct {
+		io.Reader
+		io.Closer
+	}{pcm, stream})
+	if err != nil {
+		return playback, err
 	}
-
-	fmt.Println("Audio playback complete")
-
-	// Original loop functionality
-	i := 0
-	for i < 10 {
-		fmt.Printf("Loop iteration: %d\n", i)
-		i++
+	if playback.Underruns > 0 {
+		log.Printf("[TTS] Audio arrived late %d times during playback", playback.Underruns)
 	}
 
-	fmt.Println("Wav file sent to pipe:", pipePath)
-
-	// Keep pipe open a bit longer
-	time.Sleep(5 * time.Second)
-
-	return nil
+	fmt.Printf("Played %v of audio to %s\n", playback.Duration, mic.pipePath)
+	return playback, nil
 }
 
 func homeHandler(w http.ResponseWriter, r *http.Request) {
@@ -115,6 +73,33 @@ func homeHandler(w http.ResponseWriter, r *http.Request) {
 	tmpl.Execute(w, nil)
 }
 
+// lockSession looks up the session addressed by the request and locks it for
+// the duration of the handler. It writes the error response itself and
+// returns nil if there is no such session.
+func lockSession(w http.ResponseWriter, r *http.Request) *Session {
+	session, err := sessions.Get(requestSessionID(r))
+	if err != nil {
+		sessionHTTPError(w, err)
+		return nil
+	}
+	session.mu.Lock()
+	return session
+}
+
+// requestContext returns the context for the bot calls a request makes. It
+// ends when the client disconnects or after the optional timeout parameter
+// (seconds), so a long selector wait does not outlive its caller.
+func requestContext(r *http.Request) (context.Context, context.CancelFunc) {
+	if v := r.FormValue("timeout"); v != "" {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+			return context.WithTimeout(r.Context(), time.Duration(seconds)*time.Second)
+		}
+	}
+	return context.WithCancel(r.Context())
+}
+
+// joinMeetingHandler starts the join as a background job and responds with
+// the job at once; callers follow it through GET /jobs/{id}.
 func joinMeetingHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -127,53 +112,53 @@ func joinMeetingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	fmt.Printf("Processing join meeting request for URL: %s\n", meetUrl)
-
-	botMutex.Lock()
-	defer botMutex.Unlock()
+	id := requestSessionID(r)
+	fmt.Printf("Processing join meeting request for URL: %s (session %s)\n", meetUrl, id)
 
-	// Initialize bot if not already done
-	if globalBot == nil {
-		var err error
-		globalBot, err = bot.NewBot(false) // false = not headless, show browser
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to create bot: %v", err), http.StatusInternalServerError)
-			return
-		}
-
-		err = globalBot.Initialize()
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to initialize bot: %v", err), http.StatusInternalServerError)
+	// The default session is started by the job if needed; named sessions
+	// must be created first through POST /sessions.
+	session, err := sessions.Get(id)
+	if err != nil && (id != defaultSessionID || !errors.Is(err, ErrSessionNotFound)) {
+		sessionHTTPError(w, err)
+		return
+	}
+	if session != nil {
+		state := session.Bot.State().State
+		if state == bot.StateWaitingInLobby || state == bot.StateInMeeting {
+			http.Error(w, fmt.Sprintf("Bot is already in a meeting (state %s)", state), http.StatusConflict)
 			return
 		}
 	}
 
-	// Check if already logged in
-	loggedIn, err := globalBot.IsLoggedIn()
-	if err != nil {
-		fmt.Printf("Error checking login status: %v\n", err)
+	opts := bot.JoinOptions{
+		DisplayName: r.FormValue("displayName"),
+		Password:    r.FormValue("password"),
 	}
-
-	if !loggedIn {
-		fmt.Println("Not logged in, performing login...")
-		err = globalBot.GoogleLogin()
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to login: %v", err), http.StatusInternalServerError)
+	if v := r.FormValue("admissionTimeout"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 {
+			http.Error(w, "admissionTimeout must be a positive number of seconds", http.StatusBadRequest)
 			return
 		}
-	} else {
-		fmt.Println("Already logged in, skipping login...")
+		opts.AdmissionTimeout = time.Duration(seconds) * time.Second
 	}
 
-	// Join the meeting
-	err = globalBot.JoinGoogleMeet(meetUrl)
+	// The bot picks the platform from the URL, logs in if that platform
+	// needs it and waits in the lobby until admitted
+	job, err := jobs.StartJoin(id, meetUrl, opts)
+	if errors.Is(err, ErrJobActive) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to join meeting: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to start join: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("Successfully joined the meeting"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/jobs/"+job.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(job)
 }
 
 func leaveMeetingHandler(w http.ResponseWriter, r *http.Request) {
@@ -182,16 +167,28 @@ func leaveMeetingHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	botMutex.Lock()
-	defer botMutex.Unlock()
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	// Stopping a join in progress withdraws from the lobby, which is all the
+	// leaving there is to do unless the bot was let in meanwhile
+	joinCancelled := jobs.CancelSession(ctx, requestSessionID(r))
+
+	session := lockSession(w, r)
+	if session == nil {
+		return
+	}
+	defer session.mu.Unlock()
 
-	if globalBot == nil {
-		http.Error(w, "No active bot session", http.StatusBadRequest)
+	state := session.Bot.State().State
+	if joinCancelled && state != bot.StateInMeeting && state != bot.StateWaitingInLobby {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("Join cancelled"))
 		return
 	}
 
 	// Leave the meeting gracefully
-	err := globalBot.LeaveMeeting()
+	err := session.Bot.LeaveMeeting(ctx)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to leave meeting: %v", err), http.StatusInternalServerError)
 		return
@@ -207,17 +204,17 @@ func enableMicrophoneHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	botMutex.Lock()
-	defer botMutex.Unlock()
-
-	if globalBot == nil {
-		http.Error(w, "No active bot session", http.StatusBadRequest)
+	session := lockSession(w, r)
+	if session == nil {
 		return
 	}
+	defer session.mu.Unlock()
 
 	fmt.Println("Processing enable microphone request...")
 
-	err := globalBot.EnableMicrophone()
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	err := session.Bot.EnableMicrophone(ctx)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to enable microphone: %v", err), http.StatusInternalServerError)
 		return
@@ -227,49 +224,136 @@ func enableMicrophoneHandler(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("Microphone enabled successfully"))
 }
 
-func initBotHandler(w http.ResponseWriter, r *http.Request) {
+func disableMicrophoneHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	out, err := exec.Command("pgrep", "-f", "keepalive.sh").Output()
-	if err == nil && len(out) > 0 {
-		fmt.Println("keepalive.sh already running")
-	} else {
-		// Start keepalive.sh if not running
-		cmd := exec.Command("/bin/bash", "./keepalive.sh")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		err := cmd.Start()
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to start keepalive script: %v", err), http.StatusInternalServerError)
-			return
-		}
-		fmt.Printf("Started keepalive.sh with PID %d\n", cmd.Process.Pid)
+	session := lockSession(w, r)
+	if session == nil {
+		return
 	}
+	defer session.mu.Unlock()
 
-	fmt.Println("Processing bot initialization request...")
+	fmt.Println("Processing disable microphone request...")
 
-	botMutex.Lock()
-	defer botMutex.Unlock()
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	err := session.Bot.DisableMicrophone(ctx)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Failed to disable microphone: %v", err), http.StatusInternalServerError)
+		return
+	}
 
-	// Initialize bot if not already done
-	if globalBot == nil {
-		var err error
-		globalBot, err = bot.NewBot(false) // false = not headless, show browser
-		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to create bot: %v", err), http.StatusInternalServerError)
-			return
-		}
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Microphone disabled successfully"))
+}
 
-		err = globalBot.Initialize()
+func meetingStatusHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Without a bot there is no meeting; report that rather than an error so
+	// the UI can poll this endpoint unconditionally.
+	status := &bot.MeetingStatus{State: bot.StateIdle}
+
+	session, err := sessions.Get(requestSessionID(r))
+	switch {
+	case err != nil:
+	case !session.mu.TryLock():
+		// A join or other request is using the page; answer from the
+		// lifecycle state instead of queueing behind it
+		state := session.Bot.State().State
+		status = &bot.MeetingStatus{State: state, InMeeting: state == bot.StateInMeeting}
+	default:
+		ctx, cancel := requestContext(r)
+		defer cancel()
+		status, err = session.Bot.MeetingStatus(ctx)
+		session.mu.Unlock()
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Failed to initialize bot: %v", err), http.StatusInternalServerError)
+			http.Error(w, fmt.Sprintf("Failed to get meeting status: %v", err), http.StatusInternalServerError)
 			return
 		}
 	}
 
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(status)
+}
+
+func closeBotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := requestSessionID(r)
+	fmt.Printf("Processing close request for session %s...\n", id)
+
+	// Close cancels a join that may sit in a lobby for minutes rather than
+	// waiting it out
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	err := sessions.Close(ctx, id)
+	if errors.Is(err, ErrSessionNotFound) {
+		sessionHTTPError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Bot closed with errors: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Bot closed successfully"))
+}
+
+func testVirtualMicHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fmt.Println("Processing virtual microphone test request...")
+
+	mic, err := requestMic(r)
+	if err != nil {
+		sessionHTTPError(w, err)
+		return
+	}
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	err = sendTestTone(ctx, mic)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Virtual microphone test failed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Test tone sent to virtual microphone"))
+}
+
+func initBotHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fmt.Println("Processing bot initialization request...")
+
+	// Initialize bot if not already done
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	_, err := sessions.GetOrCreate(ctx, defaultSessionID)
+	if err != nil {
+		sessionHTTPError(w, err)
+		return
+	}
+
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Bot initialized successfully"))
 }
@@ -281,11 +365,89 @@ func generateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	text := r.FormValue("text")
-	err := generateAndSendTTS(text)
+	mic, err := requestMic(r)
+	if err != nil {
+		sessionHTTPError(w, err)
+		return
+	}
+
+	// ssml is an alternative to text for control over pauses, emphasis and
+	// pronunciation
+	var text string
+	var doc *tts.SSML
+	if markup := r.FormValue("ssml"); markup != "" {
+		if r.FormValue("text") != "" {
+			http.Error(w, "send either text or ssml, not both", http.StatusBadRequest)
+			return
+		}
+		doc, err = tts.ParseSSML(markup)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		text = doc.PlainText()
+	} else {
+		text, err = tts.SanitizeText(r.FormValue("text"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	opts := SpeechOptions{
+		Priority: SpeechPriority(r.FormValue("priority")),
+		Engine:   r.FormValue("engine"),
+		Voice:    r.FormValue("voice"),
+	}
+	switch opts.Priority {
+	case "":
+		opts.Priority = SpeechNormal
+	case SpeechNormal, SpeechUrgent:
+	default:
+		http.Error(w, "priority must be normal or urgent", http.StatusBadRequest)
+		return
+	}
+	if v := r.FormValue("rate"); v != "" {
+		opts.Rate, err = strconv.Atoi(v)
+		if err != nil || opts.Rate < 20 || opts.Rate > 500 {
+			http.Error(w, "rate must be between 20 and 500 words per minute", http.StatusBadRequest)
+			return
+		}
+	}
+	if err := tts.ValidateVoice(opts.Voice); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	engine, err := ttsEngines.Get(opts.Engine)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	opts.Engine = engine.Name()
+
+	speech, err := mic.speech.Enqueue(text, doc, opts)
 	if err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	// wait=false returns as soon as the text is queued
+	if r.FormValue("wait") == "false" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(speech.Item)
+		return
+	}
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	_, err = speech.Wait(ctx)
+	switch {
+	case errors.Is(err, ErrSpeechStopped), errors.Is(err, ErrSpeechRemoved):
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	case err != nil:
 		fmt.Println("Error:", err)
-		http.Error(w, "Failed to generate and send TTS", http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Failed to generate and send TTS: %v", err), http.StatusInternalServerError)
 		return
 	}
 
@@ -299,17 +461,17 @@ func screenshotHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	botMutex.Lock()
-	defer botMutex.Unlock()
-
-	if globalBot == nil {
-		http.Error(w, "No active bot session", http.StatusBadRequest)
+	session := lockSession(w, r)
+	if session == nil {
 		return
 	}
+	defer session.mu.Unlock()
 
 	fmt.Println("Processing screenshot request...")
 
-	screenshot, err := globalBot.TakeScreenshot()
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	screenshot, err := session.Bot.TakeScreenshot(ctx)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("Failed to take screenshot: %v", err), http.StatusInternalServerError)
 		return
@@ -332,10 +494,8 @@ func botStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	botMutex.Lock()
-	defer botMutex.Unlock()
-
-	isInitialized := globalBot != nil
+	_, err := sessions.Get(requestSessionID(r))
+	isInitialized := err == nil
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -348,33 +508,198 @@ func clearPopupsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	botMutex.Lock()
-	defer botMutex.Unlock()
-
-	if globalBot == nil {
-		http.Error(w, "No active bot session", http.StatusBadRequest)
+	session := lockSession(w, r)
+	if session == nil {
 		return
 	}
+	defer session.mu.Unlock()
 
 	fmt.Println("Processing clear popups request...")
 
-	globalBot.ClearPopups()
+	ctx, cancel := requestContext(r)
+	defer cancel()
+	session.Bot.ClearPopups(ctx)
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Popups cleared successfully"))
 }
 
+func botStateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	session, err := sessions.Get(requestSessionID(r))
+	if err != nil {
+		sessionHTTPError(w, err)
+		return
+	}
+
+	// State is safe to read while another request holds the session
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(session.Bot.State())
+}
+
+func sessionsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		list := sessions.List()
+		infos := make([]SessionInfo, 0, len(list))
+		for _, session := range list {
+			infos = append(infos, session.Info())
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(infos)
+
+	case http.MethodPost:
+		fmt.Println("Processing create session request...")
+
+		ctx, cancel := requestContext(r)
+		defer cancel()
+		session, err := sessions.Create(ctx, r.FormValue("id"))
+		if err != nil {
+			sessionHTTPError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(session.Info())
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// storageStateHandler invalidates the saved Google login. Running browsers
+// are signed out first, otherwise they would write their cookies back on
+// close. Clearing cookies doesn't touch the page, so it doesn't wait for
+// the session lock held by a running join.
+func storageStateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	fmt.Println("Processing storage state removal request...")
+
+	for _, session := range sessions.List() {
+		if err := session.Bot.ClearCookies(r.Context()); err != nil {
+			log.Printf("[STORAGE_STATE] Failed to clear cookies for session %s: %v", session.ID, err)
+		}
+	}
+
+	if err := bot.RemoveStorageState(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Storage state removed"))
+}
+
 func main() {
+	if err := bot.LoadEnv(); err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+
+	maxSessions := 4
+	if v := os.Getenv("MAX_SESSIONS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			log.Fatalf("invalid MAX_SESSIONS %q: %v", v, err)
+		}
+		maxSessions = n
+	}
+	var err error
+	ttsEngines, err = tts.RegistryFromEnv()
+	if err != nil {
+		log.Fatalf("invalid TTS configuration: %v", err)
+	}
+	log.Printf("[TTS] Engines: %s (default %s)", strings.Join(ttsEngines.Names(), ", "), ttsEngines.Default)
+
+	defaultMic = newDefaultVirtualMic()
+	sessions = NewSessionManager(maxSessions, false, events) // false = not headless, show browser
+
+	// A webhook can be configured up front; more can be added over HTTP
+	if hookURL := os.Getenv("WEBHOOK_URL"); hookURL != "" {
+		events, err := parseEventTypes(os.Getenv("WEBHOOK_EVENTS"))
+		if err == nil {
+			_, err = webhooks.Add(hookURL, os.Getenv("WEBHOOK_SECRET"), "", events)
+		}
+		if err != nil {
+			log.Fatalf("invalid webhook configuration: %v", err)
+		}
+	}
+	go webhooks.Run(events)
 
 	http.HandleFunc("/", homeHandler)
 	http.HandleFunc("/generate", generateHandler)
 	http.HandleFunc("/join-meeting", joinMeetingHandler)
 	http.HandleFunc("/leave-meeting", leaveMeetingHandler)
 	http.HandleFunc("/enable-microphone", enableMicrophoneHandler)
+	http.HandleFunc("/disable-microphone", disableMicrophoneHandler)
 	http.HandleFunc("/init-bot", initBotHandler)
 	http.HandleFunc("/bot-status", botStatusHandler)
 	http.HandleFunc("/screenshot", screenshotHandler)
 	http.HandleFunc("/clear-popups", clearPopupsHandler)
+	http.HandleFunc("/meeting-status", meetingStatusHandler)
+	http.HandleFunc("/close-bot", closeBotHandler)
+	http.HandleFunc("/test-virtual-mic", testVirtualMicHandler)
+	http.HandleFunc("/bot-state", botStateHandler)
+	http.HandleFunc("/storage-state", storageStateHandler)
+	http.HandleFunc("/jobs/{id}", jobHandler)
+	http.HandleFunc("/voices", voicesHandler)
+	http.HandleFunc("/speech", speechHandler)
+	http.HandleFunc("/speech/stop", stopSpeechHandler)
+	http.HandleFunc("/speech/{item}", deleteSpeechHandler)
+	http.HandleFunc("/events", eventsHandler)
+	http.HandleFunc("/events/ws", eventsWebSocketHandler)
+	http.HandleFunc("/webhooks", webhooksHandler)
+	http.HandleFunc("/webhooks/{id}", deleteWebhookHandler)
+	http.HandleFunc("/webhooks/deliveries", webhookDeliveriesHandler)
+
+	// Per-session routes share the handlers above; requestSessionID picks
+	// the session from the {id} path segment.
+	http.HandleFunc("/sessions", sessionsHandler)
+	http.HandleFunc("/sessions/{id}", closeBotHandler)
+	http.HandleFunc("/sessions/{id}/join", joinMeetingHandler)
+	http.HandleFunc("/sessions/{id}/leave", leaveMeetingHandler)
+	http.HandleFunc("/sessions/{id}/enable-microphone", enableMicrophoneHandler)
+	http.HandleFunc("/sessions/{id}/disable-microphone", disableMicrophoneHandler)
+	http.HandleFunc("/sessions/{id}/status", meetingStatusHandler)
+	http.HandleFunc("/sessions/{id}/state", botStateHandler)
+	http.HandleFunc("/sessions/{id}/screenshot", screenshotHandler)
+	http.HandleFunc("/sessions/{id}/clear-popups", clearPopupsHandler)
+	http.HandleFunc("/sessions/{id}/generate", generateHandler)
+	http.HandleFunc("/sessions/{id}/speech", speechHandler)
+	http.HandleFunc("/sessions/{id}/speech/stop", stopSpeechHandler)
+	http.HandleFunc("/sessions/{id}/speech/{item}", deleteSpeechHandler)
+	http.HandleFunc("/sessions/{id}/test-virtual-mic", testVirtualMicHandler)
+
+	grace := shutdownTimeout()
+	server := &http.Server{Addr: ":8080"}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		serverErr <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serverErr:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
 
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	log.Printf("[SHUTDOWN] Signal received, shutting down (grace period %v)", grace)
+	shutdown(grace, server)
+	log.Printf("[SHUTDOWN] Done")
 }
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"meetbot-go-2/bot"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultSessionID names the session driven by the original top-level
+// endpoints (/join-meeting, /leave-meeting, ...). It uses the virtual mic
+// created by setup.sh rather than a dedicated one.
+const defaultSessionID = "default"
+
+// teardownLeaveTimeout bounds leaving the meeting when a session closes.
+const teardownLeaveTimeout = 30 * time.Second
+
+var (
+	ErrSessionNotFound = errors.New("session not found")
+	ErrSessionExists   = errors.New("session already exists")
+	ErrSessionLimit    = errors.New("session limit reached")
+	ErrInvalidSession  = errors.New("invalid session id")
+)
+
+// Session IDs end up in PulseAudio source names and FIFO paths.
+var sessionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// Session is one bot attending (at most) one meeting, with its own browser
+// and virtual microphone.
+type Session struct {
+	ID        string
+	Bot       *bot.Bot
+	Mic       *virtualMic
+	CreatedAt time.Time
+
+	// mu serialises operations on the bot; Playwright pages are not safe for
+	// concurrent use. A join releases it between checks while it waits in a
+	// lobby.
+	mu sync.Mutex
+}
+
+// SessionInfo is the JSON view of a session returned by the list endpoint.
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	AudioPipe string    `json:"audioPipe"`
+	CreatedAt time.Time `json:"createdAt"`
+	State     bot.State `json:"state"`
+}
+
+// Info may be called without holding s.mu; it only reads fields that are
+// fixed once the session is listed and the bot's own locked state.
+func (s *Session) Info() SessionInfo {
+	return SessionInfo{
+		ID:        s.ID,
+		AudioPipe: s.Mic.pipePath,
+		CreatedAt: s.CreatedAt,
+		State:     s.Bot.State().State,
+	}
+}
+
+// SessionManager owns every bot session in the process.
+type SessionManager struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	// pending holds IDs being created, which count towards maxSessions. The
+	// channel is closed once the session is listed or its creation failed.
+	pending     map[string]chan struct{}
+	maxSessions int
+	headless    bool
+	events      *bot.EventBus
+
+	// makeSession and startSession create a session's mic and bot and start
+	// its browser. Tests replace them, having neither PulseAudio nor a
+	// browser.
+	makeSession  func(id string) (*Session, error)
+	startSession func(ctx context.Context, s *Session) error
+}
+
+func NewSessionManager(maxSessions int, headless bool, events *bot.EventBus) *SessionManager {
+	m := &SessionManager{
+		sessions:    make(map[string]*Session),
+		pending:     make(map[string]chan struct{}),
+		maxSessions: maxSessions,
+		headless:    headless,
+		events:      events,
+		startSession: func(ctx context.Context, s *Session) error {
+			return s.Bot.Initialize(ctx)
+		},
+	}
+	m.makeSession = m.newSession
+	return m
+}
+
+// newID returns a random hex identifier for sessions, webhooks and the like.
+func newID() string {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// Create starts a new session with its own browser. An empty id generates one.
+// The session is visible to Get while the browser starts, but operations on
+// it block until initialization finishes.
+func (m *SessionManager) Create(ctx context.Context, id string) (*Session, error) {
+	if id == "" {
+		id = newID()
+	}
+	if !sessionIDPattern.MatchString(id) {
+		return nil, ErrInvalidSession
+	}
+
+	// Reserve the ID, then set up the mic and bot without holding the
+	// manager lock: loading the PulseAudio module shells out to pactl
+	m.mu.Lock()
+	if _, ok := m.sessions[id]; ok || m.pending[id] != nil {
+		m.mu.Unlock()
+		return nil, ErrSessionExists
+	}
+	if m.maxSessions > 0 && len(m.sessions)+len(m.pending) >= m.maxSessions {
+		m.mu.Unlock()
+		return nil, ErrSessionLimit
+	}
+	created := make(chan struct{})
+	m.pending[id] = created
+	m.mu.Unlock()
+
+	session, err := m.makeSession(id)
+
+	m.mu.Lock()
+	delete(m.pending, id)
+	close(created)
+	if err != nil {
+		m.mu.Unlock()
+		return nil, err
+	}
+	session.mu.Lock()
+	m.sessions[id] = session
+	m.mu.Unlock()
+
+	// Starting the browser is slow, so do it without holding the manager lock
+	fmt.Printf("Starting session %s...\n", id)
+	err = m.startSession(ctx, session)
+	if err != nil {
+		m.mu.Lock()
+		delete(m.sessions, id)
+		m.mu.Unlock()
+		session.teardown()
+	}
+	session.mu.Unlock()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize bot: %v", err)
+	}
+	return session, nil
+}
+
+// newSession creates the virtual mic and (not yet initialized) bot for a
+// session. Both are fixed for the lifetime of the session.
+func (m *SessionManager) newSession(id string) (*Session, error) {
+	session := &Session{
+		ID:        id,
+		CreatedAt: time.Now(),
+	}
+
+	var err error
+	if id == defaultSessionID {
+		session.Mic = defaultMic
+	} else {
+		session.Mic, err = newVirtualMic(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create virtual mic: %v", err)
+		}
+	}
+
+	session.Bot, err = bot.NewBot(m.headless)
+	if err != nil {
+		session.Mic.Close()
+		return nil, fmt.Errorf("failed to create bot: %v", err)
+	}
+	if id != defaultSessionID {
+		session.Bot.SetAudioSource(session.Mic.source)
+	}
+	session.Bot.SetEventBus(m.events, id)
+
+	return session, nil
+}
+
+func (m *SessionManager) Get(id string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	session, ok := m.sessions[id]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return session, nil
+}
+
+// GetOrCreate returns the session, starting it first if it does not exist.
+func (m *SessionManager) GetOrCreate(ctx context.Context, id string) (*Session, error) {
+	for {
+		session, err := m.Get(id)
+		if err == nil {
+			return session, nil
+		}
+
+		session, err = m.Create(ctx, id)
+		if !errors.Is(err, ErrSessionExists) {
+			return session, err
+		}
+
+		// Lost a race with another request creating the same session. Wait
+		// until it is listed, or its creation failed and we can try again.
+		m.mu.Lock()
+		created := m.pending[id]
+		m.mu.Unlock()
+		if created != nil {
+			select {
+			case <-created:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+	}
+}
+
+// List returns all sessions ordered by creation time.
+func (m *SessionManager) List() []*Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]*Session, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		list = append(list, session)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].CreatedAt.Before(list[j].CreatedAt)
+	})
+	return list
+}
+
+// Close removes a session, leaving its meeting if it is in one, and releases
+// its browser and virtual mic. A join running on the session is cancelled
+// first and given until ctx ends to stop; one still running after that finds
+// the bot gone when it next takes the session lock.
+func (m *SessionManager) Close(ctx context.Context, id string) error {
+	m.mu.Lock()
+	session, ok := m.sessions[id]
+	if !ok {
+		m.mu.Unlock()
+		return ErrSessionNotFound
+	}
+	delete(m.sessions, id)
+	m.mu.Unlock()
+
+	jobs.CancelSession(ctx, id)
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+
+	return session.teardown()
+}
+
+// CloseAll closes every session at once, as Close does. It gives up waiting
+// when ctx expires, leaving the remaining teardowns to finish or die with the
+// process.
+func (m *SessionManager) CloseAll(ctx context.Context) error {
+	list := m.List()
+	errs := make(chan error, len(list))
+	var wg sync.WaitGroup
+	for _, session := range list {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := m.Close(ctx, session.ID); err != nil && !errors.Is(err, ErrSessionNotFound) {
+				errs <- fmt.Errorf("session %s: %v", session.ID, err)
+			}
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	close(errs)
+	var all []error
+	for err := range errs {
+		all = append(all, err)
+	}
+	return errors.Join(all...)
+}
+
+// teardown leaves the meeting and releases resources. The caller holds
+// session.mu and has already removed the session from the manager.
+func (s *Session) teardown() error {
+	fmt.Printf("Closing session %s...\n", s.ID)
+
+	var firstErr error
+
+	// Leave the meeting first so other participants see the bot go. This
+	// runs on its own deadline so teardown still happens when the request
+	// that triggered it has gone away.
+	state := s.Bot.State().State
+	if state == bot.StateInMeeting || state == bot.StateWaitingInLobby {
+		ctx, cancel := context.WithTimeout(context.Background(), teardownLeaveTimeout)
+		defer cancel()
+		if err := s.Bot.LeaveMeeting(ctx); err != nil {
+			fmt.Printf("Failed to leave meeting before closing session %s: %v\n", s.ID, err)
+		}
+	}
+
+	if err := s.Bot.Close(); err != nil {
+		firstErr = err
+	}
+
+	if err := s.Mic.Close(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+
+	return firstErr
+}
+
+// sessionHTTPError maps session manager errors to HTTP responses.
+func sessionHTTPError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		http.Error(w, "No active bot session", http.StatusNotFound)
+	case errors.Is(err, ErrSessionExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrSessionLimit):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, ErrInvalidSession):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// requestMic returns the virtual mic of the session addressed by the request.
+// The default mic is always available, even before its session is started.
+func requestMic(r *http.Request) (*virtualMic, error) {
+	id := requestSessionID(r)
+	if id == defaultSessionID {
+		return defaultMic, nil
+	}
+	session, err := sessions.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.Mic, nil
+}
+
+// requestSessionID returns the session addressed by the request. Routes under
+// /sessions/{id}/ name it explicitly; the top-level routes use the default
+// session.
+func requestSessionID(r *http.Request) string {
+	if id := r.PathValue("id"); id != "" {
+		return id
+	}
+	return defaultSessionID
+}
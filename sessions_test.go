@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"meetbot-go-2/bot"
+)
+
+// fakeSessions returns a manager whose sessions have neither a PulseAudio
+// source nor a browser. Creating a session blocks on release, if set, after
+// reporting its ID on made.
+type fakeSessions struct {
+	*SessionManager
+	made    chan string
+	release chan struct{}
+	fail    error
+	calls   atomic.Int32
+}
+
+func newFakeSessions(maxSessions int) *fakeSessions {
+	f := &fakeSessions{
+		SessionManager: NewSessionManager(maxSessions, true, nil),
+		made:           make(chan string, 16),
+	}
+	f.makeSession = func(id string) (*Session, error) {
+		f.calls.Add(1)
+		f.made <- id
+		if f.release != nil {
+			<-f.release
+		}
+		if f.fail != nil {
+			return nil, f.fail
+		}
+		return &Session{
+			ID:        id,
+			Bot:       &bot.Bot{},
+			Mic:       &virtualMic{pipePath: "/tmp/meetbot-" + id},
+			CreatedAt: time.Now(),
+		}, nil
+	}
+	f.startSession = func(ctx context.Context, s *Session) error { return nil }
+	return f
+}
+
+func TestCreateSessionID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want error
+	}{
+		{"standup", nil},
+		{"Team_1-a", nil},
+		{strings.Repeat("a", 64), nil},
+		{strings.Repeat("a", 65), ErrInvalidSession},
+		{"../etc", ErrInvalidSession},
+		{"two words", ErrInvalidSession},
+		{"room.1", ErrInvalidSession},
+		{"café", ErrInvalidSession},
+	}
+	for _, tt := range tests {
+		m := newFakeSessions(0)
+		session, err := m.Create(context.Background(), tt.id)
+		if !errors.Is(err, tt.want) {
+			t.Errorf("Create(%q) error = %v, want %v", tt.id, err, tt.want)
+			continue
+		}
+		if err == nil && session.ID != tt.id {
+			t.Errorf("Create(%q) created %q", tt.id, session.ID)
+		}
+		if err != nil && m.calls.Load() != 0 {
+			t.Errorf("Create(%q) set up a session for an invalid ID", tt.id)
+		}
+	}
+
+	// An empty ID gets a generated one
+	m := newFakeSessions(0)
+	session, err := m.Create(context.Background(), "")
+	if err != nil || !sessionIDPattern.MatchString(session.ID) {
+		t.Errorf("Create(\"\") = %v, %v, want a generated ID", session, err)
+	}
+	if _, err := m.Create(context.Background(), session.ID); !errors.Is(err, ErrSessionExists) {
+		t.Errorf("Create(%q) again error = %v, want %v", session.ID, err, ErrSessionExists)
+	}
+}
+
+func TestCreateSessionLimit(t *testing.T) {
+	m := newFakeSessions(2)
+	m.release = make(chan struct{})
+
+	// A session still being set up counts towards the limit, and setting it
+	// up does not hold the manager lock
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.Create(context.Background(), "slow")
+		done <- err
+	}()
+	<-m.made
+	if _, err := m.Get("slow"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("Get(slow) while creating = %v, want %v", err, ErrSessionNotFound)
+	}
+	if _, err := m.Create(context.Background(), "slow"); !errors.Is(err, ErrSessionExists) {
+		t.Errorf("Create(slow) while creating = %v, want %v", err, ErrSessionExists)
+	}
+	close(m.release)
+	if _, err := m.Create(context.Background(), "second"); err != nil {
+		t.Fatalf("Create(second) error = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Fatalf("Create(slow) error = %v", err)
+	}
+
+	if _, err := m.Create(context.Background(), "third"); !errors.Is(err, ErrSessionLimit) {
+		t.Errorf("Create(third) error = %v, want %v", err, ErrSessionLimit)
+	}
+	if got := len(m.List()); got != 2 {
+		t.Errorf("List() has %d sessions, want 2", got)
+	}
+}
+
+func TestCreateSessionFailure(t *testing.T) {
+	m := newFakeSessions(1)
+	m.fail = errors.New("failed to create virtual mic: pactl not found")
+
+	if _, err := m.Create(context.Background(), "standup"); !errors.Is(err, m.fail) {
+		t.Fatalf("Create() error = %v, want %v", err, m.fail)
+	}
+
+	// The reservation is released: neither the ID nor the slot is taken
+	m.fail = nil
+	if _, err := m.Create(context.Background(), "standup"); err != nil {
+		t.Fatalf("Create() after a failure error = %v", err)
+	}
+}
+
+func TestGetOrCreateConcurrent(t *testing.T) {
+	m := newFakeSessions(0)
+	m.release = make(chan struct{})
+
+	const callers = 8
+	results := make(chan *Session, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			session, err := m.GetOrCreate(ctx, "shared")
+			if err != nil {
+				t.Errorf("GetOrCreate() error = %v", err)
+			}
+			results <- session
+		}()
+	}
+	<-m.made
+	// Let the other callers find the creation in progress
+	time.Sleep(20 * time.Millisecond)
+	close(m.release)
+	wg.Wait()
+	close(results)
+
+	first := <-results
+	for session := range results {
+		if session != first {
+			t.Errorf("GetOrCreate() returned different sessions %p and %p", session, first)
+		}
+	}
+	if calls := m.calls.Load(); calls != 1 {
+		t.Errorf("created the session %d times, want once", calls)
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// defaultShutdownTimeout is how long a SIGTERM may take to drain requests,
+// leave meetings and close the browsers. Docker only waits 10s by default, so
+// build.sh runs the container with --stop-timeout 35.
+const defaultShutdownTimeout = 30 * time.Second
+
+// shuttingDown is closed when the server starts shutting down. Event streams
+// never finish on their own, so they watch it to let Shutdown drain.
+var shuttingDown = make(chan struct{})
+
+// shutdownTimeout reads SHUTDOWN_TIMEOUT as a Go duration.
+func shutdownTimeout() time.Duration {
+	v := os.Getenv("SHUTDOWN_TIMEOUT")
+	if v == "" {
+		return defaultShutdownTimeout
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatalf("invalid SHUTDOWN_TIMEOUT %q: must be a positive duration such as 30s", v)
+	}
+	return d
+}
+
+// shutdown cancels join jobs and ends event streams, stops accepting
+// requests and waits for running ones and for the joins to stop, takes every
+// bot out of its meeting and closes the browsers, then stops feeding the
+// default mic and abandons webhook retries. Draining requests may use up to a
+// third of grace; stopping joins and closing sessions get the rest, so a slow
+// drain cannot stop the bots from leaving their meetings. Anything still
+// running after grace is abandoned.
+func shutdown(grace time.Duration, server *http.Server) {
+	closeCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+
+	// Cancel joins first: a join waiting in a lobby holds its session and
+	// with it any request queued behind it
+	close(shuttingDown)
+	joinsStopped := make(chan struct{})
+	go func() {
+		jobs.CancelAll(closeCtx)
+		close(joinsStopped)
+	}()
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), grace/3)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err != nil {
+		log.Printf("[SHUTDOWN] HTTP server did not drain: %v", err)
+		server.Close()
+	}
+
+	// A cancelled join withdraws its request to join before it stops;
+	// closing its browser first would cut that short
+	<-joinsStopped
+	if err := sessions.CloseAll(closeCtx); err != nil {
+		log.Printf("[SHUTDOWN] Sessions did not close cleanly: %v", err)
+	}
+
+	defaultMic.speech.Close()
+	defaultMic.mixer.Close()
+	webhooks.Close()
+}
@@ -0,0 +1,413 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"meetbot-go-2/audio"
+	"meetbot-go-2/bot"
+	"meetbot-go-2/tts"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	ErrSpeechNotFound = errors.New("speech item not found")
+	ErrNothingPlaying = errors.New("nothing is playing")
+	ErrSpeechStopped  = errors.New("speech was stopped")
+	ErrSpeechRemoved  = errors.New("speech was removed from the queue")
+	ErrSpeechClosed   = errors.New("microphone closed")
+)
+
+type SpeechPriority string
+
+const (
+	SpeechNormal SpeechPriority = "normal"
+	SpeechUrgent SpeechPriority = "urgent" // queued ahead of every normal item
+)
+
+type SpeechStatus string
+
+const (
+	SpeechQueued  SpeechStatus = "queued"
+	SpeechPlaying SpeechStatus = "playing"
+)
+
+// SpeechOptions controls how an utterance is spoken.
+type SpeechOptions struct {
+	Priority SpeechPriority `json:"priority"`
+	Engine   string         `json:"engine"`
+	Voice    string         `json:"voice,omitempty"` // empty uses the engine's default
+	Rate     int            `json:"rate,omitempty"`  // words per minute, 0 for the engine's default
+}
+
+// SpeechItem is one utterance waiting for or playing through a mic. Fields
+// are guarded by the queue's lock; handlers only see copies.
+type SpeechItem struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	SSML string `json:"ssml,omitempty"` // the markup Text came from, if any
+	SpeechOptions
+	Status    SpeechStatus `json:"status"`
+	CreatedAt time.Time    `json:"createdAt"`
+	StartedAt *time.Time   `json:"startedAt,omitempty"`
+
+	doc      *tts.SSML
+	cancel   context.CancelCauseFunc
+	done     chan struct{}
+	playback audio.Playback
+	err      error
+}
+
+// SpeechQueue plays utterances through a mic one at a time, urgent ones
+// first. Everything spoken through a mic goes through its queue, so
+// concurrent requests take turns instead of mixing their audio.
+type SpeechQueue struct {
+	sessionID string // event source
+	speak     func(ctx context.Context, item *SpeechItem) (audio.Playback, error)
+
+	mu      sync.Mutex
+	queued  []*SpeechItem
+	current *SpeechItem
+	closed  bool
+
+	wake   chan struct{}
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewSpeechQueue(mic *virtualMic, sessionID string) *SpeechQueue {
+	return newSpeechQueue(sessionID, func(ctx context.Context, item *SpeechItem) (audio.Playback, error) {
+		return generateAndSendTTS(ctx, item.Text, item.doc, item.SpeechOptions, mic)
+	})
+}
+
+// newSpeechQueue starts a queue that plays each item with speak, which
+// returns once the item has been heard or ctx is cancelled.
+func newSpeechQueue(sessionID string, speak func(ctx context.Context, item *SpeechItem) (audio.Playback, error)) *SpeechQueue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &SpeechQueue{
+		sessionID: sessionID,
+		speak:     speak,
+		wake:      make(chan struct{}, 1),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	go q.run(ctx)
+	return q
+}
+
+// SpeechHandle follows an item from Enqueue until it has finished, even once
+// it has left the queue.
+type SpeechHandle struct {
+	// Item is a copy of the item as it was queued.
+	Item SpeechItem
+
+	queue *SpeechQueue
+	item  *SpeechItem
+}
+
+// Enqueue adds text to the queue, or an SSML document when doc is not nil.
+// Urgent items go behind other urgent items but ahead of normal ones; the
+// item playing now is never interrupted.
+func (q *SpeechQueue) Enqueue(text string, doc *tts.SSML, opts SpeechOptions) (*SpeechHandle, error) {
+	item := &SpeechItem{
+		ID:            newID(),
+		Text:          text,
+		doc:           doc,
+		SpeechOptions: opts,
+		Status:        SpeechQueued,
+		CreatedAt:     time.Now(),
+		done:          make(chan struct{}),
+	}
+	if doc != nil {
+		item.SSML = doc.String()
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return nil, ErrSpeechClosed
+	}
+
+	i := len(q.queued)
+	if opts.Priority == SpeechUrgent {
+		i = 0
+		for i < len(q.queued) && q.queued[i].Priority == SpeechUrgent {
+			i++
+		}
+	}
+	q.queued = append(q.queued, nil)
+	copy(q.queued[i+1:], q.queued[i:])
+	q.queued[i] = item
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return &SpeechHandle{Item: *item, queue: q, item: item}, nil
+}
+
+// Wait blocks until the item has finished playing and returns how it went,
+// including when it failed or was removed before Wait was called. If ctx is
+// done first the item is removed or stopped.
+func (h *SpeechHandle) Wait(ctx context.Context) (audio.Playback, error) {
+	select {
+	case <-h.item.done:
+	case <-ctx.Done():
+		h.queue.Remove(h.item.ID)
+		<-h.item.done
+	}
+	return h.item.playback, h.item.err
+}
+
+// Stop cuts off the item that is playing. The next one starts straight away.
+func (q *SpeechQueue) Stop() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.current == nil {
+		return ErrNothingPlaying
+	}
+	q.current.cancel(ErrSpeechStopped)
+	return nil
+}
+
+// Remove drops a queued item, or stops it if it is already playing.
+func (q *SpeechQueue) Remove(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.current != nil && q.current.ID == id {
+		q.current.cancel(ErrSpeechStopped)
+		return nil
+	}
+	for i, item := range q.queued {
+		if item.ID == id {
+			q.queued = append(q.queued[:i], q.queued[i+1:]...)
+			q.endLocked(item, audio.Playback{}, ErrSpeechRemoved)
+			return nil
+		}
+	}
+	return ErrSpeechNotFound
+}
+
+// List returns the playing item, if any, followed by the queue in play order.
+func (q *SpeechQueue) List() []SpeechItem {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	list := make([]SpeechItem, 0, len(q.queued)+1)
+	if q.current != nil {
+		list = append(list, *q.current)
+	}
+	for _, item := range q.queued {
+		list = append(list, *item)
+	}
+	return list
+}
+
+// Close stops playback and fails everything still queued.
+func (q *SpeechQueue) Close() {
+	q.mu.Lock()
+	q.closed = true
+	for _, item := range q.queued {
+		q.endLocked(item, audio.Playback{}, ErrSpeechClosed)
+	}
+	q.queued = nil
+	if q.current != nil {
+		q.current.cancel(ErrSpeechClosed)
+	}
+	q.mu.Unlock()
+
+	q.cancel()
+	<-q.done
+}
+
+func (q *SpeechQueue) run(ctx context.Context) {
+	defer close(q.done)
+	for {
+		item, itemCtx := q.next()
+		if item == nil {
+			select {
+			case <-q.wake:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		q.play(itemCtx, item)
+	}
+}
+
+// next moves the first queued item to playing.
+func (q *SpeechQueue) next() (*SpeechItem, context.Context) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.queued) == 0 || q.closed {
+		return nil, nil
+	}
+	item := q.queued[0]
+	q.queued = q.queued[1:]
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	now := time.Now()
+	item.Status = SpeechPlaying
+	item.StartedAt = &now
+	item.cancel = cancel
+	q.current = item
+	return item, ctx
+}
+
+func (q *SpeechQueue) play(ctx context.Context, item *SpeechItem) {
+	publishEvent(bot.EventTTSStarted, q.sessionID, map[string]any{
+		"id":       item.ID,
+		"text":     item.Text,
+		"priority": item.Priority,
+		"engine":   item.Engine,
+	})
+
+	playback, err := q.speak(ctx, item)
+	if cause := context.Cause(ctx); cause != nil && errors.Is(err, context.Canceled) {
+		err = cause
+	}
+
+	data := map[string]any{
+		"id":         item.ID,
+		"text":       item.Text,
+		"durationMs": time.Since(*item.StartedAt).Milliseconds(),
+		"audioMs":    playback.Duration.Milliseconds(),
+		"underruns":  playback.Underruns,
+	}
+	switch {
+	case err == nil:
+		publishEvent(bot.EventTTSFinished, q.sessionID, data)
+	case errors.Is(err, ErrSpeechStopped) || errors.Is(err, ErrSpeechClosed):
+		data["stopped"] = true
+		publishEvent(bot.EventTTSFinished, q.sessionID, data)
+	default:
+		log.Printf("[SPEECH] Failed to speak %s: %v", item.ID, err)
+		publishEvent(bot.EventError, q.sessionID, map[string]any{"error": err.Error(), "context": "tts", "id": item.ID})
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	item.cancel(nil)
+	q.current = nil
+	q.endLocked(item, playback, err)
+}
+
+func (q *SpeechQueue) endLocked(item *SpeechItem, playback audio.Playback, err error) {
+	item.playback = playback
+	item.err = err
+	close(item.done)
+}
+
+// voicesHandler lists the voices of every TTS engine, or of the one named
+// by the engine parameter.
+func voicesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	names := ttsEngines.Names()
+	if name := r.FormValue("engine"); name != "" {
+		if _, err := ttsEngines.Get(name); err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		names = []string{name}
+	}
+
+	ctx, cancel := requestContext(r)
+	defer cancel()
+
+	voices := []tts.Voice{}
+	for _, name := range names {
+		engine, _ := ttsEngines.Get(name)
+		list, err := engine.Voices(ctx)
+		if err != nil {
+			if len(names) == 1 {
+				http.Error(w, err.Error(), http.StatusBadGateway)
+				return
+			}
+			log.Printf("[TTS] Failed to list %s voices: %v", name, err)
+			continue
+		}
+		voices = append(voices, list...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]any{
+		"defaultEngine": ttsEngines.Default,
+		"voices":        voices,
+	})
+}
+
+// speechHandler lists the mic's queue.
+func speechHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mic, err := requestMic(r)
+	if err != nil {
+		sessionHTTPError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(mic.speech.List())
+}
+
+// stopSpeechHandler cuts off whatever the mic is saying.
+func stopSpeechHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mic, err := requestMic(r)
+	if err != nil {
+		sessionHTTPError(w, err)
+		return
+	}
+
+	if err := mic.speech.Stop(); err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Speech stopped"))
+}
+
+// deleteSpeechHandler drops an item from the queue, stopping it if it is
+// already playing.
+func deleteSpeechHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	mic, err := requestMic(r)
+	if err != nil {
+		sessionHTTPError(w, err)
+		return
+	}
+
+	if err := mic.speech.Remove(r.PathValue("item")); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Speech removed"))
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"meetbot-go-2/audio"
+)
+
+// fakeSpeaker stands in for synthesis and the mic. Each item reports its
+// text on started and then plays until the test sends its result on finish
+// or the queue cancels it.
+type fakeSpeaker struct {
+	started chan string
+	finish  chan error
+}
+
+func newFakeQueue(t *testing.T) (*SpeechQueue, *fakeSpeaker) {
+	t.Helper()
+	f := &fakeSpeaker{started: make(chan string, 16), finish: make(chan error)}
+	q := newSpeechQueue("speech-test", func(ctx context.Context, item *SpeechItem) (audio.Playback, error) {
+		f.started <- item.Text
+		select {
+		case err := <-f.finish:
+			return audio.Playback{Duration: time.Second, Frames: 50}, err
+		case <-ctx.Done():
+			return audio.Playback{}, ctx.Err()
+		}
+	})
+	t.Cleanup(q.Close)
+	return q, f
+}
+
+// next returns the text of the item that starts playing next.
+func (f *fakeSpeaker) next(t *testing.T) string {
+	t.Helper()
+	select {
+	case text := <-f.started:
+		return text
+	case <-time.After(5 * time.Second):
+		t.Fatal("nothing started playing")
+		return ""
+	}
+}
+
+func enqueue(t *testing.T, q *SpeechQueue, text string, priority SpeechPriority) *SpeechHandle {
+	t.Helper()
+	h, err := q.Enqueue(text, nil, SpeechOptions{Priority: priority})
+	if err != nil {
+		t.Fatalf("Enqueue(%q): %v", text, err)
+	}
+	return h
+}
+
+func wait(t *testing.T, h *SpeechHandle) (audio.Playback, error) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return h.Wait(ctx)
+}
+
+func TestSpeechWaitAfterFinish(t *testing.T) {
+	q, f := newFakeQueue(t)
+	synthErr := errors.New("espeak-ng failed: voice does not exist")
+
+	h := enqueue(t, q, "hello", SpeechNormal)
+	f.next(t)
+	f.finish <- synthErr
+	<-h.item.done // finished, and gone from the queue, before Wait is called
+
+	if _, err := wait(t, h); !errors.Is(err, synthErr) {
+		t.Errorf("Wait = %v, want the synthesis error", err)
+	}
+
+	h = enqueue(t, q, "again", SpeechNormal)
+	f.next(t)
+	f.finish <- nil
+	<-h.item.done
+	if playback, err := wait(t, h); err != nil || playback.Frames != 50 {
+		t.Errorf("Wait = %+v, %v; want the playback", playback, err)
+	}
+}
+
+func TestSpeechPriority(t *testing.T) {
+	q, f := newFakeQueue(t)
+
+	// Hold the queue on a first item so the rest line up behind it
+	first := enqueue(t, q, "first", SpeechNormal)
+	f.next(t)
+	handles := []*SpeechHandle{
+		enqueue(t, q, "normal 1", SpeechNormal),
+		enqueue(t, q, "normal 2", SpeechNormal),
+		enqueue(t, q, "urgent 1", SpeechUrgent),
+		enqueue(t, q, "normal 3", SpeechNormal),
+		enqueue(t, q, "urgent 2", SpeechUrgent),
+	}
+
+	want := []string{"first", "urgent 1", "urgent 2", "normal 1", "normal 2", "normal 3"}
+	var listed []string
+	for _, item := range q.List() {
+		listed = append(listed, item.Text)
+	}
+	if !slices.Equal(listed, want) {
+		t.Errorf("List = %q, want %q", listed, want)
+	}
+	if items := q.List(); items[0].Status != SpeechPlaying || items[1].Status != SpeechQueued {
+		t.Errorf("statuses = %s, %s; want playing, queued", items[0].Status, items[1].Status)
+	}
+
+	f.finish <- nil
+	played := []string{"first"}
+	for range handles {
+		played = append(played, f.next(t))
+		f.finish <- nil
+	}
+	if !slices.Equal(played, want) {
+		t.Errorf("played %q, want %q", played, want)
+	}
+	for _, h := range append(handles, first) {
+		if _, err := wait(t, h); err != nil {
+			t.Errorf("%s: %v", h.Item.Text, err)
+		}
+	}
+}
+
+func TestSpeechRemove(t *testing.T) {
+	q, f := newFakeQueue(t)
+
+	playing := enqueue(t, q, "playing", SpeechNormal)
+	f.next(t)
+	removed := enqueue(t, q, "removed", SpeechNormal)
+	kept := enqueue(t, q, "kept", SpeechNormal)
+
+	if err := q.Remove(removed.Item.ID); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if _, err := wait(t, removed); !errors.Is(err, ErrSpeechRemoved) {
+		t.Errorf("Wait on the removed item = %v, want ErrSpeechRemoved", err)
+	}
+	if err := q.Remove(removed.Item.ID); !errors.Is(err, ErrSpeechNotFound) {
+		t.Errorf("removing it again = %v, want ErrSpeechNotFound", err)
+	}
+
+	// Removing the playing item stops it
+	if err := q.Remove(playing.Item.ID); err != nil {
+		t.Fatalf("Remove playing: %v", err)
+	}
+	if _, err := wait(t, playing); !errors.Is(err, ErrSpeechStopped) {
+		t.Errorf("Wait on the playing item = %v, want ErrSpeechStopped", err)
+	}
+
+	if text := f.next(t); text != "kept" {
+		t.Errorf("next played %q, want %q", text, "kept")
+	}
+	f.finish <- nil
+	if _, err := wait(t, kept); err != nil {
+		t.Errorf("Wait on the kept item: %v", err)
+	}
+}
+
+func TestSpeechStop(t *testing.T) {
+	q, f := newFakeQueue(t)
+
+	if err := q.Stop(); !errors.Is(err, ErrNothingPlaying) {
+		t.Errorf("Stop while idle = %v, want ErrNothingPlaying", err)
+	}
+
+	playing := enqueue(t, q, "playing", SpeechNormal)
+	f.next(t)
+	rest := []*SpeechHandle{
+		enqueue(t, q, "next 1", SpeechNormal),
+		enqueue(t, q, "next 2", SpeechNormal),
+	}
+
+	if err := q.Stop(); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if _, err := wait(t, playing); !errors.Is(err, ErrSpeechStopped) {
+		t.Errorf("Wait on the stopped item = %v, want ErrSpeechStopped", err)
+	}
+
+	// The rest of the queue plays on, and stopping each drains it
+	for _, h := range rest {
+		if text := f.next(t); text != h.Item.Text {
+			t.Errorf("played %q, want %q", text, h.Item.Text)
+		}
+		if err := q.Stop(); err != nil {
+			t.Fatalf("Stop: %v", err)
+		}
+		if _, err := wait(t, h); !errors.Is(err, ErrSpeechStopped) {
+			t.Errorf("Wait on %q = %v, want ErrSpeechStopped", h.Item.Text, err)
+		}
+	}
+	if list := q.List(); len(list) != 0 {
+		t.Errorf("queue still holds %d items", len(list))
+	}
+}
+
+func TestSpeechWaitCancelled(t *testing.T) {
+	q, f := newFakeQueue(t)
+
+	playing := enqueue(t, q, "playing", SpeechNormal)
+	f.next(t)
+	queued := enqueue(t, q, "queued", SpeechNormal)
+
+	// A caller that gives up takes its item out of the queue
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := queued.Wait(ctx); !errors.Is(err, ErrSpeechRemoved) {
+		t.Errorf("Wait = %v, want ErrSpeechRemoved", err)
+	}
+	if _, err := playing.Wait(ctx); !errors.Is(err, ErrSpeechStopped) {
+		t.Errorf("Wait on the playing item = %v, want ErrSpeechStopped", err)
+	}
+}
+
+func TestSpeechClose(t *testing.T) {
+	q, f := newFakeQueue(t)
+
+	playing := enqueue(t, q, "playing", SpeechNormal)
+	f.next(t)
+	queued := enqueue(t, q, "queued", SpeechNormal)
+
+	q.Close()
+	if _, err := wait(t, playing); !errors.Is(err, ErrSpeechClosed) {
+		t.Errorf("Wait on the playing item = %v, want ErrSpeechClosed", err)
+	}
+	if _, err := wait(t, queued); !errors.Is(err, ErrSpeechClosed) {
+		t.Errorf("Wait on the queued item = %v, want ErrSpeechClosed", err)
+	}
+	if _, err := q.Enqueue("late", nil, SpeechOptions{}); !errors.Is(err, ErrSpeechClosed) {
+		t.Errorf("Enqueue after Close = %v, want ErrSpeechClosed", err)
+	}
+}
@@ -0,0 +1,125 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"meetbot-go-2/audio"
+	"os"
+	"sort"
+)
+
+var ErrUnknownEngine = errors.New("unknown TTS engine")
+
+// Engine turns text into speech.
+type Engine interface {
+	// Name identifies the engine in requests and listings, e.g. "espeak-ng".
+	Name() string
+
+	// Voices lists the voices Synthesize accepts.
+	Voices(ctx context.Context) ([]Voice, error)
+
+	// Synthesize speaks text in voice at rate words per minute. An empty
+	// voice or zero rate uses the engine's default. The stream may still be
+	// being produced while it is read; closing it stops synthesis.
+	Synthesize(ctx context.Context, text, voice string, rate int) (*Stream, error)
+}
+
+// SSMLEngine is implemented by engines that can speak SSML themselves.
+// Other engines are given the document's plain text by SynthesizeSSML.
+type SSMLEngine interface {
+	SynthesizeSSML(ctx context.Context, doc *SSML, voice string, rate int) (*Stream, error)
+}
+
+// SynthesizeSSML speaks doc with engine, using as much of the markup as the
+// engine understands.
+func SynthesizeSSML(ctx context.Context, engine Engine, doc *SSML, voice string, rate int) (*Stream, error) {
+	if ssmlEngine, ok := engine.(SSMLEngine); ok {
+		return ssmlEngine.SynthesizeSSML(ctx, doc, voice, rate)
+	}
+	return engine.Synthesize(ctx, doc.PlainText(), voice, rate)
+}
+
+// Voice is one voice an engine offers.
+type Voice struct {
+	Engine   string `json:"engine"`
+	ID       string `json:"id"` // passed back as the voice parameter
+	Name     string `json:"name"`
+	Language string `json:"language,omitempty"`
+}
+
+// Stream is synthesized speech as raw PCM in Format.
+type Stream struct {
+	Format audio.Format
+	io.ReadCloser
+}
+
+// readCloser reads decoded audio and closes its source.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// normalRate is the speaking rate, in words per minute, that engines without
+// a words-per-minute setting treat as their natural speed.
+const normalRate = 175
+
+// Registry holds the configured engines.
+type Registry struct {
+	engines map[string]Engine
+	// Default is the engine used when a request names none.
+	Default string
+}
+
+func NewRegistry(defaultEngine string, engines ...Engine) (*Registry, error) {
+	r := &Registry{engines: make(map[string]Engine), Default: defaultEngine}
+	for _, engine := range engines {
+		r.engines[engine.Name()] = engine
+	}
+	if _, ok := r.engines[defaultEngine]; !ok {
+		return nil, fmt.Errorf("%w: default %q is not configured", ErrUnknownEngine, defaultEngine)
+	}
+	return r, nil
+}
+
+// RegistryFromEnv configures espeak-ng, plus Piper when its binary is
+// installed and the HTTP engine when TTS_HTTP_URL is set. TTS_ENGINE picks
+// the default.
+func RegistryFromEnv() (*Registry, error) {
+	engines := []Engine{NewEspeak()}
+	if piper, err := PiperFromEnv(); err == nil {
+		engines = append(engines, piper)
+	}
+	if endpoint := os.Getenv("TTS_HTTP_URL"); endpoint != "" {
+		engines = append(engines, HTTPFromEnv(endpoint))
+	}
+
+	defaultEngine := os.Getenv("TTS_ENGINE")
+	if defaultEngine == "" {
+		defaultEngine = espeakName
+	}
+	return NewRegistry(defaultEngine, engines...)
+}
+
+// Get returns the named engine, or the default for "".
+func (r *Registry) Get(name string) (Engine, error) {
+	if name == "" {
+		name = r.Default
+	}
+	engine, ok := r.engines[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEngine, name)
+	}
+	return engine, nil
+}
+
+// Names lists the configured engines in alphabetical order.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.engines))
+	for name := range r.engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,105 @@
+package tts
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"meetbot-go-2/audio"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+const (
+	espeakName = "espeak-ng"
+	// espeakDefaultRate is deliberately slow; it carries better over meeting
+	// audio than espeak-ng's own default of 175.
+	espeakDefaultRate = 65
+)
+
+// Espeak is the espeak-ng formant synthesizer. It is robotic but small, fast
+// and always installed in the container.
+type Espeak struct {
+	bin string
+}
+
+func NewEspeak() *Espeak {
+	return &Espeak{bin: "espeak-ng"}
+}
+
+func (e *Espeak) Name() string {
+	return espeakName
+}
+
+// Voices parses espeak-ng --voices, whose rows look like
+//
+//	Pty Language       Age/Gender VoiceName          File                 Other Languages
+//	 5  af              --/M      Afrikaans          gmw/af
+func (e *Espeak) Voices(ctx context.Context) ([]Voice, error) {
+	out, err := exec.CommandContext(ctx, e.bin, "--voices").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list espeak-ng voices: %v", err)
+	}
+
+	var voices []Voice
+	lines := strings.Split(string(out), "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			continue
+		}
+		voices = append(voices, Voice{
+			Engine:   espeakName,
+			ID:       fields[1],
+			Name:     fields[3],
+			Language: fields[1],
+		})
+	}
+	return voices, nil
+}
+
+// Synthesize speaks plain text; any "<" in it is read out, not parsed.
+func (e *Espeak) Synthesize(ctx context.Context, text, voice string, rate int) (*Stream, error) {
+	return e.synthesize(ctx, text, voice, rate, false)
+}
+
+// SynthesizeSSML speaks the document with espeak-ng's own SSML support.
+func (e *Espeak) SynthesizeSSML(ctx context.Context, doc *SSML, voice string, rate int) (*Stream, error) {
+	return e.synthesize(ctx, doc.espeakMarkup(), voice, rate, true)
+}
+
+// synthesize feeds text to espeak-ng on stdin, so nothing in it can be read
+// as an option, and decodes the WAV it writes to stdout. With markup set,
+// espeak-ng interprets SSML tags in the text.
+func (e *Espeak) synthesize(ctx context.Context, text, voice string, rate int, markup bool) (*Stream, error) {
+	if err := ValidateVoice(voice); err != nil {
+		return nil, err
+	}
+	if rate == 0 {
+		rate = espeakDefaultRate
+	}
+	args := []string{"--stdin", "--stdout", "-s", strconv.Itoa(rate)}
+	if voice != "" {
+		args = append(args, "-v", voice)
+	}
+	if markup {
+		args = append(args, "-m")
+	}
+
+	cmd := exec.CommandContext(ctx, e.bin, args...)
+	cmd.Stdin = strings.NewReader(text)
+	output, err := startProcess(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	wav, err := audio.DecodeWAV(bufio.NewReader(output))
+	if err != nil {
+		output.Close()
+		return nil, fmt.Errorf("failed to decode espeak-ng output: %w", err)
+	}
+	return &Stream{
+		Format:     wav.Format,
+		ReadCloser: readCloser{wav.Data, output},
+	}, nil
+}
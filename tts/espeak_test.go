@@ -0,0 +1,203 @@
+package tts
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// wavFile wraps 16-bit mono samples in a minimal WAV file.
+func wavFile(sampleRate int, data []byte) []byte {
+	var wav []byte
+	wav = append(wav, "RIFF"...)
+	wav = binary.LittleEndian.AppendUint32(wav, uint32(36+len(data)))
+	wav = append(wav, "WAVEfmt \x10\x00\x00\x00"...)
+	wav = binary.LittleEndian.AppendUint16(wav, 1) // PCM
+	wav = binary.LittleEndian.AppendUint16(wav, 1) // mono
+	wav = binary.LittleEndian.AppendUint32(wav, uint32(sampleRate))
+	wav = binary.LittleEndian.AppendUint32(wav, uint32(sampleRate*2))
+	wav = binary.LittleEndian.AppendUint16(wav, 2)  // block align
+	wav = binary.LittleEndian.AppendUint16(wav, 16) // bits per sample
+	wav = append(wav, "data"...)
+	wav = binary.LittleEndian.AppendUint32(wav, uint32(len(data)))
+	return append(wav, data...)
+}
+
+// stubEspeak installs a script in place of espeak-ng that records its
+// arguments and stdin in dir and answers with a short WAV file.
+func stubEspeak(t *testing.T) (e *Espeak, dir string) {
+	t.Helper()
+	dir = t.TempDir()
+
+	wav := wavFile(22050, make([]byte, 8))
+	if err := os.WriteFile(filepath.Join(dir, "out.wav"), wav, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	script := `#!/bin/sh
+for arg in "$@"; do printf '%s\0' "$arg"; done > "` + dir + `/argv"
+cat > "` + dir + `/stdin"
+cat "` + dir + `/out.wav"
+`
+	bin := filepath.Join(dir, "espeak-ng")
+	if err := os.WriteFile(bin, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return &Espeak{bin: bin}, dir
+}
+
+func TestEspeakSpeaksHostileText(t *testing.T) {
+	e, dir := stubEspeak(t)
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payloads := []string{
+		"$(touch pwned)",
+		"`touch pwned`",
+		"hello; rm -rf " + dir + "; touch pwned",
+		`it's "quoted" \" ' && touch pwned`,
+		"-w" + filepath.Join(dir, "x"),
+		"--stdout -w " + filepath.Join(dir, "x"),
+		"line one\nline two\n$(touch pwned)",
+		"| touch pwned > pwned",
+	}
+	for _, payload := range payloads {
+		t.Run(payload, func(t *testing.T) {
+			text, err := SanitizeText(payload)
+			if err != nil {
+				t.Fatalf("SanitizeText: %v", err)
+			}
+
+			stream, err := e.Synthesize(context.Background(), text, "", 0)
+			if err != nil {
+				t.Fatalf("Synthesize: %v", err)
+			}
+			_, err = io.ReadAll(stream)
+			stream.Close()
+			if err != nil {
+				t.Fatalf("reading speech: %v", err)
+			}
+
+			argv, err := os.ReadFile(filepath.Join(dir, "argv"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			args := strings.Split(strings.TrimSuffix(string(argv), "\x00"), "\x00")
+			if want := []string{"--stdin", "--stdout", "-s", "65"}; !slices.Equal(args, want) {
+				t.Errorf("args = %q, want %q", args, want)
+			}
+
+			stdin, err := os.ReadFile(filepath.Join(dir, "stdin"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(stdin) != text {
+				t.Errorf("stdin = %q, want the text %q", stdin, text)
+			}
+
+			for _, path := range []string{
+				filepath.Join(cwd, "pwned"),
+				filepath.Join(dir, "pwned"),
+				filepath.Join(dir, "x"),
+			} {
+				if _, err := os.Stat(path); err == nil {
+					os.Remove(path)
+					t.Errorf("%s was created", path)
+				}
+			}
+			if _, err := os.Stat(filepath.Join(dir, "out.wav")); err != nil {
+				t.Errorf("the stub's files were removed: %v", err)
+			}
+		})
+	}
+}
+
+func TestEspeakArguments(t *testing.T) {
+	e, dir := stubEspeak(t)
+
+	stream, err := e.Synthesize(context.Background(), "hello", "en-us", 120)
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	io.ReadAll(stream)
+	stream.Close()
+
+	argv, _ := os.ReadFile(filepath.Join(dir, "argv"))
+	args := strings.Split(strings.TrimSuffix(string(argv), "\x00"), "\x00")
+	if want := []string{"--stdin", "--stdout", "-s", "120", "-v", "en-us"}; !slices.Equal(args, want) {
+		t.Errorf("args = %q, want %q", args, want)
+	}
+
+	if _, err := e.Synthesize(context.Background(), "hello", "-w/tmp/x", 0); err == nil {
+		t.Error("Synthesize accepted an option as the voice")
+	}
+}
+
+func TestSanitizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+		err  error
+	}{
+		{"plain", "Hello team", "Hello team", nil},
+		{"trimmed", "  hi \n", "hi", nil},
+		{"line breaks and tabs kept", "one\ntwo\tthree", "one\ntwo\tthree", nil},
+		{"control characters", "bell\a null\x00 esc\x1b[31m", "bell  null  esc [31m", nil},
+		{"invalid UTF-8", "caf\xe9", "caf", nil},
+		{"shell syntax is text", "$(rm -rf /) `id` ; | &&", "$(rm -rf /) `id` ; | &&", nil},
+		{"unicode", "Grüße, 日本語", "Grüße, 日本語", nil},
+		{"empty", "", "", ErrEmptyText},
+		{"only whitespace", " \n\t ", "", ErrEmptyText},
+		{"only control characters", "\x00\x01", "", ErrEmptyText},
+		{"at the limit", strings.Repeat("é", MaxTextLength), strings.Repeat("é", MaxTextLength), nil},
+		{"too long", strings.Repeat("a", MaxTextLength+1), "", ErrTextTooLong},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SanitizeText(tt.text)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateVoice(t *testing.T) {
+	tests := []struct {
+		voice string
+		ok    bool
+	}{
+		{"", true},
+		{"en-us", true},
+		{"en_US-lessac-medium", true},
+		{"mb-en1", true},
+		{"en+f3", true},
+		{"gmw/en-US", false},
+		{"-w/tmp/x", false},
+		{"--stdout", false},
+		{"../../etc/passwd", false},
+		{".hidden", false},
+		{"en us", false},
+		{"en;rm", false},
+		{"$(id)", false},
+		{strings.Repeat("a", 64), true},
+		{strings.Repeat("a", 65), false},
+	}
+	for _, tt := range tests {
+		err := ValidateVoice(tt.voice)
+		if (err == nil) != tt.ok {
+			t.Errorf("ValidateVoice(%q) = %v, want ok %v", tt.voice, err, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package tts
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// processOutput streams a synthesizer's stdout. A failed exit is reported by
+// Read in place of EOF, with whatever the process wrote to stderr.
+type processOutput struct {
+	cmd    *exec.Cmd
+	stdout io.ReadCloser
+	stderr bytes.Buffer
+
+	once    sync.Once
+	waitErr error
+}
+
+// startProcess starts cmd and returns its output.
+func startProcess(cmd *exec.Cmd) (*processOutput, error) {
+	p := &processOutput{cmd: cmd}
+	cmd.Stderr = &p.stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	p.stdout = stdout
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %v", filepath.Base(cmd.Path), err)
+	}
+	return p, nil
+}
+
+func (p *processOutput) Read(b []byte) (int, error) {
+	n, err := p.stdout.Read(b)
+	if err == io.EOF {
+		if werr := p.wait(); werr != nil {
+			return n, werr
+		}
+	}
+	return n, err
+}
+
+// Close stops the process if it is still running.
+func (p *processOutput) Close() error {
+	p.once.Do(func() {
+		p.cmd.Process.Kill()
+		p.cmd.Wait()
+	})
+	return nil
+}
+
+func (p *processOutput) wait() error {
+	p.once.Do(func() {
+		if err := p.cmd.Wait(); err != nil {
+			p.waitErr = fmt.Errorf("%s failed: %v: %s", filepath.Base(p.cmd.Path), err, strings.TrimSpace(p.stderr.String()))
+		}
+	})
+	return p.waitErr
+}
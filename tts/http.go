@@ -0,0 +1,151 @@
+package tts
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"meetbot-go-2/audio"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	httpName         = "http"
+	defaultHTTPModel = "tts-1"
+	// httpResponseTimeout bounds the wait for the server to start answering.
+	// The body is read as the speech plays, so it has no overall timeout.
+	httpResponseTimeout = 60 * time.Second
+)
+
+var defaultHTTPVoices = []string{"alloy", "echo", "fable", "onyx", "nova", "shimmer"}
+
+// HTTP calls a speech endpoint with the OpenAI /v1/audio/speech interface,
+// which many hosted and self-hosted TTS servers also implement. The
+// response must be a WAV file.
+type HTTP struct {
+	endpoint string
+	apiKey   string
+	model    string
+	voices   []string
+	// SSML sends SSML documents as the input instead of their plain text,
+	// for servers that accept markup.
+	SSML   bool
+	client *http.Client
+}
+
+// HTTPFromEnv configures the engine for endpoint from TTS_HTTP_API_KEY,
+// TTS_HTTP_MODEL, TTS_HTTP_VOICES (comma separated; the first is the
+// default) and TTS_HTTP_SSML.
+func HTTPFromEnv(endpoint string) *HTTP {
+	model := os.Getenv("TTS_HTTP_MODEL")
+	if model == "" {
+		model = defaultHTTPModel
+	}
+	voices := defaultHTTPVoices
+	if v := os.Getenv("TTS_HTTP_VOICES"); v != "" {
+		voices = nil
+		for _, voice := range strings.Split(v, ",") {
+			if voice = strings.TrimSpace(voice); voice != "" {
+				voices = append(voices, voice)
+			}
+		}
+	}
+	engine := NewHTTP(endpoint, os.Getenv("TTS_HTTP_API_KEY"), model, voices)
+	engine.SSML = os.Getenv("TTS_HTTP_SSML") == "true"
+	return engine
+}
+
+func NewHTTP(endpoint, apiKey, model string, voices []string) *HTTP {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = httpResponseTimeout
+	return &HTTP{
+		endpoint: endpoint,
+		apiKey:   apiKey,
+		model:    model,
+		voices:   voices,
+		client:   &http.Client{Transport: transport},
+	}
+}
+
+func (h *HTTP) Name() string {
+	return httpName
+}
+
+// Voices returns the configured list; the API has no way to ask.
+func (h *HTTP) Voices(ctx context.Context) ([]Voice, error) {
+	voices := make([]Voice, 0, len(h.voices))
+	for _, voice := range h.voices {
+		voices = append(voices, Voice{Engine: httpName, ID: voice, Name: voice})
+	}
+	return voices, nil
+}
+
+// SynthesizeSSML sends the markup only to servers known to accept it.
+func (h *HTTP) SynthesizeSSML(ctx context.Context, doc *SSML, voice string, rate int) (*Stream, error) {
+	if h.SSML {
+		return h.Synthesize(ctx, doc.String(), voice, rate)
+	}
+	return h.Synthesize(ctx, doc.PlainText(), voice, rate)
+}
+
+type speechRequest struct {
+	Model          string  `json:"model"`
+	Input          string  `json:"input"`
+	Voice          string  `json:"voice"`
+	ResponseFormat string  `json:"response_format"`
+	Speed          float64 `json:"speed,omitempty"`
+}
+
+func (h *HTTP) Synthesize(ctx context.Context, text, voice string, rate int) (*Stream, error) {
+	if voice == "" && len(h.voices) > 0 {
+		voice = h.voices[0]
+	}
+	body := speechRequest{
+		Model:          h.model,
+		Input:          text,
+		Voice:          voice,
+		ResponseFormat: "wav",
+	}
+	if rate > 0 {
+		// The API takes a speed multiplier between 0.25 and 4
+		body.Speed = min(max(float64(rate)/normalRate, 0.25), 4)
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("invalid TTS_HTTP_URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if h.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+h.apiKey)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("TTS request failed: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("TTS server returned %s: %s", resp.Status, strings.TrimSpace(string(message)))
+	}
+
+	wav, err := audio.DecodeWAV(bufio.NewReader(resp.Body))
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to decode TTS server response: %w", err)
+	}
+	return &Stream{
+		Format:     wav.Format,
+		ReadCloser: readCloser{wav.Data, resp.Body},
+	}, nil
+}
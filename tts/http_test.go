@@ -0,0 +1,196 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"meetbot-go-2/audio"
+)
+
+// stubSpeechServer records the requests it gets and answers them with
+// respond.
+func stubSpeechServer(t *testing.T, respond http.HandlerFunc) (*httptest.Server, *[]*http.Request, *[]speechRequest) {
+	t.Helper()
+	var requests []*http.Request
+	var bodies []speechRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body speechRequest
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("request body is not JSON: %v", err)
+		}
+		requests = append(requests, r)
+		bodies = append(bodies, body)
+		respond(w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &bodies
+}
+
+func TestHTTPSynthesize(t *testing.T) {
+	samples := []byte{1, 0, 2, 0, 3, 0, 4, 0}
+	server, requests, bodies := stubSpeechServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "audio/wav")
+		w.Write(wavFile(24000, samples))
+	})
+
+	engine := NewHTTP(server.URL+"/v1/audio/speech", "sk-test", "tts-1-hd", []string{"nova", "alloy"})
+	stream, err := engine.Synthesize(context.Background(), `Say "hi" & go`, "", 350)
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	defer stream.Close()
+
+	want := audio.Format{SampleRate: 24000, Channels: 1, BitsPerSample: 16, Encoding: audio.EncodingPCM}
+	if stream.Format != want {
+		t.Errorf("format = %v, want %v", stream.Format, want)
+	}
+	data, err := io.ReadAll(stream)
+	if err != nil {
+		t.Fatalf("reading speech: %v", err)
+	}
+	if string(data) != string(samples) {
+		t.Errorf("samples = %v, want %v", data, samples)
+	}
+
+	r := (*requests)[0]
+	if r.Method != http.MethodPost || r.URL.Path != "/v1/audio/speech" {
+		t.Errorf("request = %s %s, want POST /v1/audio/speech", r.Method, r.URL.Path)
+	}
+	if got := r.Header.Get("Authorization"); got != "Bearer sk-test" {
+		t.Errorf("Authorization = %q, want %q", got, "Bearer sk-test")
+	}
+	if got := r.Header.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+	wantBody := speechRequest{
+		Model:          "tts-1-hd",
+		Input:          `Say "hi" & go`,
+		Voice:          "nova", // the first configured voice is the default
+		ResponseFormat: "wav",
+		Speed:          2,
+	}
+	if got := (*bodies)[0]; got != wantBody {
+		t.Errorf("body = %+v, want %+v", got, wantBody)
+	}
+}
+
+func TestHTTPSpeed(t *testing.T) {
+	server, _, bodies := stubSpeechServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(wavFile(24000, nil))
+	})
+	engine := NewHTTP(server.URL, "", "tts-1", nil)
+
+	tests := []struct {
+		rate  int
+		speed float64
+	}{
+		{0, 0}, // omitted, the server's default
+		{175, 1},
+		{20, 0.25},
+		{35, 0.25},
+		{500, 500.0 / 175},
+		{1000, 4},
+	}
+	for i, tt := range tests {
+		stream, err := engine.Synthesize(context.Background(), "hello", "alloy", tt.rate)
+		if err != nil {
+			t.Fatalf("Synthesize at rate %d: %v", tt.rate, err)
+		}
+		stream.Close()
+		if got := (*bodies)[i].Speed; got != tt.speed {
+			t.Errorf("rate %d: speed = %v, want %v", tt.rate, got, tt.speed)
+		}
+	}
+}
+
+func TestHTTPNoAPIKey(t *testing.T) {
+	server, requests, _ := stubSpeechServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write(wavFile(24000, nil))
+	})
+	engine := NewHTTP(server.URL, "", "tts-1", nil)
+
+	stream, err := engine.Synthesize(context.Background(), "hello", "alloy", 0)
+	if err != nil {
+		t.Fatalf("Synthesize: %v", err)
+	}
+	stream.Close()
+	if got := (*requests)[0].Header.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want none without an API key", got)
+	}
+}
+
+func TestHTTPErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		respond http.HandlerFunc
+		want    string
+	}{
+		{
+			name: "unauthorized",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, `{"error":{"message":"Incorrect API key provided"}}`, http.StatusUnauthorized)
+			},
+			want: `TTS server returned 401 Unauthorized: {"error":{"message":"Incorrect API key provided"}}`,
+		},
+		{
+			name: "long error body is cut short",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, strings.Repeat("x", 2000), http.StatusInternalServerError)
+			},
+			want: "TTS server returned 500 Internal Server Error: " + strings.Repeat("x", 512),
+		},
+		{
+			name: "not a WAV file",
+			respond: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "audio/mpeg")
+				w.Write([]byte("ID3\x04\x00\x00\x00\x00\x00\x00\x00\x00 mp3 frames"))
+			},
+			want: "failed to decode TTS server response: not a RIFF/WAVE file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _, _ := stubSpeechServer(t, tt.respond)
+			engine := NewHTTP(server.URL, "sk-test", "tts-1", nil)
+
+			_, err := engine.Synthesize(context.Background(), "hello", "alloy", 0)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("err = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestHTTPSSML(t *testing.T) {
+	doc, err := ParseSSML(`<speak>Hello <break time="300ms"/>team</speak>`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, ssml := range []bool{false, true} {
+		server, _, bodies := stubSpeechServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write(wavFile(24000, nil))
+		})
+		engine := NewHTTP(server.URL, "", "tts-1", nil)
+		engine.SSML = ssml
+
+		stream, err := SynthesizeSSML(context.Background(), engine, doc, "alloy", 0)
+		if err != nil {
+			t.Fatalf("SynthesizeSSML: %v", err)
+		}
+		stream.Close()
+
+		want := doc.PlainText()
+		if ssml {
+			want = doc.String()
+		}
+		if got := (*bodies)[0].Input; got != want {
+			t.Errorf("SSML %v: input = %q, want %q", ssml, got, want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"meetbot-go-2/audio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	piperName             = "piper"
+	defaultPiperVoicesDir = "/usr/share/piper-voices"
+)
+
+// Piper runs local neural voices on the CPU. Each voice is an ONNX model
+// with a JSON config next to it, as downloaded from the Piper voice list.
+type Piper struct {
+	bin       string
+	voicesDir string
+	// defaultVoice is used when a request names none.
+	defaultVoice string
+}
+
+// PiperFromEnv configures Piper from PIPER_BIN, PIPER_VOICES_DIR and
+// PIPER_DEFAULT_VOICE. It fails if the binary is not installed.
+func PiperFromEnv() (*Piper, error) {
+	bin := os.Getenv("PIPER_BIN")
+	if bin == "" {
+		bin = "piper"
+	}
+	bin, err := exec.LookPath(bin)
+	if err != nil {
+		return nil, fmt.Errorf("piper is not installed: %v", err)
+	}
+
+	voicesDir := os.Getenv("PIPER_VOICES_DIR")
+	if voicesDir == "" {
+		voicesDir = defaultPiperVoicesDir
+	}
+	return &Piper{
+		bin:          bin,
+		voicesDir:    voicesDir,
+		defaultVoice: os.Getenv("PIPER_DEFAULT_VOICE"),
+	}, nil
+}
+
+func (p *Piper) Name() string {
+	return piperName
+}
+
+// piperConfig is the part of a voice's .onnx.json this engine needs.
+type piperConfig struct {
+	Audio struct {
+		SampleRate int `json:"sample_rate"`
+	} `json:"audio"`
+	Language struct {
+		Code string `json:"code"`
+	} `json:"language"`
+	Dataset string `json:"dataset"`
+}
+
+// Voices lists the models in the voices directory. Voice IDs are the model
+// file names without .onnx, e.g. "en_US-lessac-medium".
+func (p *Piper) Voices(ctx context.Context) ([]Voice, error) {
+	models, err := filepath.Glob(filepath.Join(p.voicesDir, "*.onnx"))
+	if err != nil {
+		return nil, err
+	}
+
+	var voices []Voice
+	for _, model := range models {
+		id := strings.TrimSuffix(filepath.Base(model), ".onnx")
+		config, err := p.config(id)
+		if err != nil {
+			continue
+		}
+		voices = append(voices, Voice{
+			Engine:   piperName,
+			ID:       id,
+			Name:     config.Dataset,
+			Language: config.Language.Code,
+		})
+	}
+	return voices, nil
+}
+
+func (p *Piper) config(voice string) (*piperConfig, error) {
+	data, err := os.ReadFile(filepath.Join(p.voicesDir, voice+".onnx.json"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown piper voice %q: %v", voice, err)
+	}
+	var config piperConfig
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("invalid config for piper voice %q: %v", voice, err)
+	}
+	return &config, nil
+}
+
+// Synthesize has Piper read text from stdin and write raw 16-bit mono PCM
+// at the voice's sample rate.
+func (p *Piper) Synthesize(ctx context.Context, text, voice string, rate int) (*Stream, error) {
+	if voice == "" {
+		voice = p.defaultVoice
+	}
+	if voice == "" {
+		return nil, fmt.Errorf("piper needs a voice, one of the models in %s", p.voicesDir)
+	}
+	if err := ValidateVoice(voice); err != nil {
+		return nil, err
+	}
+	config, err := p.config(voice)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"--model", filepath.Join(p.voicesDir, voice+".onnx"), "--output_raw"}
+	if rate > 0 {
+		// Piper stretches phoneme length rather than setting a rate
+		lengthScale := float64(normalRate) / float64(rate)
+		args = append(args, "--length_scale", strconv.FormatFloat(lengthScale, 'f', 2, 64))
+	}
+
+	cmd := exec.CommandContext(ctx, p.bin, args...)
+	cmd.Stdin = strings.NewReader(text)
+	output, err := startProcess(cmd)
+	if err != nil {
+		return nil, err
+	}
+	return &Stream{
+		Format: audio.Format{
+			SampleRate:    config.Audio.SampleRate,
+			Channels:      1,
+			BitsPerSample: 16,
+			Encoding:      audio.EncodingPCM,
+		},
+		ReadCloser: output,
+	}, nil
+}
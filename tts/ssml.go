@@ -0,0 +1,399 @@
+package tts
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrInvalidSSML = errors.New("invalid SSML")
+
+// maxSSMLLength bounds the markup; the spoken text inside it is held to
+// MaxTextLength like plain text.
+const maxSSMLLength = 4 * MaxTextLength
+
+// maxBreak is the longest pause a <break> may ask for.
+const maxBreak = 10 * time.Second
+
+// SSML is a validated Speech Synthesis Markup Language document. Only the
+// elements meeting announcements need are accepted: <break>, <emphasis>,
+// <say-as>, <prosody> and <phoneme>, with <p> and <s> for structure.
+type SSML struct {
+	root *ssmlNode
+}
+
+// ssmlNode is an element, or a run of text when name is "".
+type ssmlNode struct {
+	name     string
+	attrs    map[string]string
+	text     string
+	children []*ssmlNode
+}
+
+// ssmlElement describes what an element may hold.
+type ssmlElement struct {
+	attrs    map[string]*regexp.Regexp // allowed attributes and their values
+	required []string
+	empty    bool // no content, like <break/>
+	textOnly bool // text but no child elements
+}
+
+var (
+	breakTime     = regexp.MustCompile(`^\d+(\.\d+)?(ms|s)$`)
+	relativeValue = `[+-]?\d+(\.\d+)?`
+	ssmlElements  = map[string]ssmlElement{
+		"speak": {attrs: map[string]*regexp.Regexp{
+			"version": regexp.MustCompile(`^1\.[01]$`),
+			"lang":    regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{1,8})*$`),
+		}},
+		"p": {},
+		"s": {},
+		"break": {
+			attrs: map[string]*regexp.Regexp{
+				"time":     breakTime,
+				"strength": regexp.MustCompile(`^(none|x-weak|weak|medium|strong|x-strong)$`),
+			},
+			empty: true,
+		},
+		"emphasis": {attrs: map[string]*regexp.Regexp{
+			"level": regexp.MustCompile(`^(strong|moderate|none|reduced)$`),
+		}},
+		"say-as": {
+			attrs: map[string]*regexp.Regexp{
+				"interpret-as": regexp.MustCompile(`^(characters|spell-out|digits|cardinal|number|ordinal|date|time|telephone|verbatim)$`),
+				"format":       regexp.MustCompile(`^[a-z]{1,8}$`),
+			},
+			required: []string{"interpret-as"},
+			textOnly: true,
+		},
+		"prosody": {attrs: map[string]*regexp.Regexp{
+			"rate":   regexp.MustCompile(`^(x-slow|slow|medium|fast|x-fast|default|` + relativeValue + `%)$`),
+			"pitch":  regexp.MustCompile(`^(x-low|low|medium|high|x-high|default|` + relativeValue + `(%|st|Hz))$`),
+			"volume": regexp.MustCompile(`^(silent|x-soft|soft|medium|loud|x-loud|default|` + relativeValue + `dB)$`),
+		}},
+		"phoneme": {
+			attrs: map[string]*regexp.Regexp{
+				"alphabet": regexp.MustCompile(`^(ipa|x-sampa|x-espeak)$`),
+				"ph":       regexp.MustCompile(`^[^<>&\[\]]{1,200}$`),
+			},
+			required: []string{"ph"},
+			textOnly: true,
+		},
+	}
+)
+
+// ParseSSML validates markup and returns the document. Errors wrap
+// ErrInvalidSSML and say where in the markup the problem is.
+func ParseSSML(markup string) (*SSML, error) {
+	if len(markup) > maxSSMLLength {
+		return nil, fmt.Errorf("%w: longer than %d bytes", ErrInvalidSSML, maxSSMLLength)
+	}
+
+	d := xml.NewDecoder(strings.NewReader(markup))
+	invalid := func(format string, args ...any) error {
+		line, col := d.InputPos()
+		return fmt.Errorf("%w: line %d, column %d: %s", ErrInvalidSSML, line, col, fmt.Sprintf(format, args...))
+	}
+
+	var root *ssmlNode
+	var stack []*ssmlNode
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var syntaxErr *xml.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				return nil, fmt.Errorf("%w: line %d: %s", ErrInvalidSSML, syntaxErr.Line, syntaxErr.Msg)
+			}
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSSML, err)
+		}
+
+		switch tok := tok.(type) {
+		case xml.StartElement:
+			name := tok.Name.Local
+			element, ok := ssmlElements[name]
+			if !ok {
+				return nil, invalid("unsupported element <%s>", name)
+			}
+			switch {
+			case root == nil && name != "speak":
+				return nil, invalid("the document must start with <speak>")
+			case root != nil && len(stack) == 0:
+				return nil, invalid("content after </speak>")
+			case root != nil && name == "speak":
+				return nil, invalid("<speak> cannot be nested")
+			}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				if ssmlElements[parent.name].empty || ssmlElements[parent.name].textOnly {
+					return nil, invalid("<%s> cannot contain <%s>", parent.name, name)
+				}
+			}
+
+			node := &ssmlNode{name: name, attrs: make(map[string]string)}
+			for _, attr := range tok.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				pattern, ok := element.attrs[attr.Name.Local]
+				if !ok {
+					return nil, invalid("<%s> does not support attribute %q", name, attr.Name.Local)
+				}
+				if !pattern.MatchString(attr.Value) {
+					return nil, invalid("invalid %s %q on <%s>", attr.Name.Local, attr.Value, name)
+				}
+				node.attrs[attr.Name.Local] = attr.Value
+			}
+			for _, attr := range element.required {
+				if _, ok := node.attrs[attr]; !ok {
+					return nil, invalid("<%s> is missing the %s attribute", name, attr)
+				}
+			}
+			if name == "break" {
+				if t, ok := node.attrs["time"]; ok && breakDuration(t) > maxBreak {
+					return nil, invalid("break of %s is longer than %v", t, maxBreak)
+				}
+			}
+
+			if root == nil {
+				root = node
+			} else {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			}
+			stack = append(stack, node)
+
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+
+		case xml.CharData:
+			if len(stack) == 0 {
+				if strings.TrimSpace(string(tok)) != "" {
+					return nil, invalid("text outside <speak>")
+				}
+				continue
+			}
+			parent := stack[len(stack)-1]
+			if ssmlElements[parent.name].empty && strings.TrimSpace(string(tok)) != "" {
+				return nil, invalid("<%s> cannot contain text", parent.name)
+			}
+			parent.children = append(parent.children, &ssmlNode{text: cleanText(string(tok))})
+
+		case xml.Directive:
+			return nil, invalid("DOCTYPE and other directives are not allowed")
+		}
+	}
+
+	if root == nil {
+		return nil, fmt.Errorf("%w: no <speak> element", ErrInvalidSSML)
+	}
+	doc := &SSML{root: root}
+
+	// breaks alone render as punctuation, which is still nothing to say
+	text := doc.PlainText()
+	if strings.IndexFunc(text, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSSML, ErrEmptyText)
+	}
+	if utf8.RuneCountInString(text) > MaxTextLength {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSSML, ErrTextTooLong)
+	}
+	return doc, nil
+}
+
+// cleanText replaces control characters as SanitizeText does.
+func cleanText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return ' '
+		}
+		return r
+	}, text)
+}
+
+// breakDuration parses a validated break time.
+func breakDuration(t string) time.Duration {
+	unit := time.Second
+	number := strings.TrimSuffix(t, "s")
+	if strings.HasSuffix(number, "m") {
+		unit = time.Millisecond
+		number = strings.TrimSuffix(number, "m")
+	}
+	v, _ := strconv.ParseFloat(number, 64)
+	return time.Duration(v * float64(unit))
+}
+
+// String returns the document as standard SSML.
+func (s *SSML) String() string {
+	var b strings.Builder
+	s.root.write(&b, nil)
+	return b.String()
+}
+
+// espeakMarkup returns the document in the SSML dialect espeak-ng reads
+// with -m. It lacks <phoneme> and most say-as types, so those are rewritten:
+// x-espeak phonemes become espeak's inline [[...]] notation, other alphabets
+// fall back to the element's text, and digits are spaced out.
+func (s *SSML) espeakMarkup() string {
+	var b strings.Builder
+	s.root.write(&b, func(b *strings.Builder, n *ssmlNode) bool {
+		switch n.name {
+		case "phoneme":
+			if n.attrs["alphabet"] == "x-espeak" {
+				b.WriteString("[[" + n.attrs["ph"] + "]]")
+			} else {
+				xml.EscapeText(b, []byte(n.innerText()))
+			}
+			return true
+		case "say-as":
+			switch n.attrs["interpret-as"] {
+			case "characters", "spell-out":
+				b.WriteString(`<say-as interpret-as="characters">`)
+				xml.EscapeText(b, []byte(n.innerText()))
+				b.WriteString(`</say-as>`)
+			case "digits", "telephone":
+				xml.EscapeText(b, []byte(spaced(n.innerText(), unicode.IsDigit)))
+			default:
+				xml.EscapeText(b, []byte(n.innerText()))
+			}
+			return true
+		}
+		return false
+	})
+	return b.String()
+}
+
+// PlainText returns what the document says, for engines without SSML
+// support. Spelled-out text is spaced into letters and breaks become commas
+// or line breaks, which most engines pause at.
+func (s *SSML) PlainText() string {
+	var b strings.Builder
+	s.root.plain(&b)
+
+	var lines []string
+	for _, line := range strings.Split(b.String(), "\n") {
+		line = strings.ReplaceAll(strings.Join(strings.Fields(line), " "), " ,", ",")
+		if line != "" && line != "," {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (n *ssmlNode) plain(b *strings.Builder) {
+	if n.name == "" {
+		b.WriteString(n.text)
+		return
+	}
+	switch n.name {
+	case "break":
+		strength := n.attrs["strength"]
+		switch {
+		case strength == "none":
+		case strength == "x-weak" || strength == "weak":
+			b.WriteString(", ")
+		case breakDuration(n.attrs["time"]) >= 500*time.Millisecond ||
+			strength == "strong" || strength == "x-strong":
+			b.WriteString("\n")
+		default:
+			b.WriteString(", ")
+		}
+		return
+	case "say-as":
+		switch n.attrs["interpret-as"] {
+		case "characters", "spell-out":
+			b.WriteString(spaced(n.innerText(), func(rune) bool { return true }))
+		case "digits", "telephone":
+			b.WriteString(spaced(n.innerText(), unicode.IsDigit))
+		default:
+			b.WriteString(n.innerText())
+		}
+		return
+	}
+	block := n.name == "p" || n.name == "s"
+	if block {
+		b.WriteString("\n")
+	}
+	for _, child := range n.children {
+		child.plain(b)
+	}
+	if block {
+		b.WriteString("\n")
+	}
+}
+
+// write serializes the element. override may write a replacement for an
+// element and return true.
+func (n *ssmlNode) write(b *strings.Builder, override func(*strings.Builder, *ssmlNode) bool) {
+	if n.name == "" {
+		xml.EscapeText(b, []byte(n.text))
+		return
+	}
+	if override != nil && override(b, n) {
+		return
+	}
+
+	b.WriteString("<" + n.name)
+	if n.name == "speak" {
+		b.WriteString(` version="1.0" xmlns="http://www.w3.org/2001/10/synthesis"`)
+		if lang, ok := n.attrs["lang"]; ok {
+			b.WriteString(` xml:lang="` + lang + `"`)
+		}
+	} else {
+		for _, attr := range sortedKeys(n.attrs) {
+			b.WriteString(" " + attr + `="`)
+			xml.EscapeText(b, []byte(n.attrs[attr]))
+			b.WriteString(`"`)
+		}
+	}
+	if ssmlElements[n.name].empty {
+		b.WriteString("/>")
+		return
+	}
+	b.WriteString(">")
+	for _, child := range n.children {
+		child.write(b, override)
+	}
+	b.WriteString("</" + n.name + ">")
+}
+
+func (n *ssmlNode) innerText() string {
+	if n.name == "" {
+		return n.text
+	}
+	var text strings.Builder
+	for _, child := range n.children {
+		text.WriteString(child.innerText())
+	}
+	return strings.TrimSpace(text.String())
+}
+
+// spaced puts a space between the runes of s that match keep, so engines
+// read them one at a time; other runes are dropped.
+func spaced(s string, keep func(rune) bool) string {
+	var runes []string
+	for _, r := range s {
+		if keep(r) && !unicode.IsSpace(r) {
+			runes = append(runes, string(r))
+		}
+	}
+	return strings.Join(runes, " ")
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,189 @@
+package tts
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSSML(t *testing.T) {
+	valid := []struct {
+		name   string
+		markup string
+	}{
+		{"plain text", `<speak>Hello team</speak>`},
+		{"namespace and version", `<speak version="1.1" xmlns="http://www.w3.org/2001/10/synthesis" xml:lang="en-US">Hello</speak>`},
+		{"paragraphs and sentences", `<speak><p><s>One.</s><s>Two.</s></p></speak>`},
+		{"break time", `<speak>Wait<break time="1.5s"/>go<break time="250ms"/>now</speak>`},
+		{"break strength", `<speak>Wait<break strength="x-strong"/>go</speak>`},
+		{"longest break", `<speak>Wait<break time="10s"/>go</speak>`},
+		{"emphasis", `<speak><emphasis level="reduced">quietly</emphasis> said</speak>`},
+		{"say-as", `<speak><say-as interpret-as="date" format="dmy">1/2/2025</say-as></speak>`},
+		{"prosody", `<speak><prosody rate="-10%" pitch="120Hz" volume="+3dB">Hi</prosody></speak>`},
+		{"prosody keywords", `<speak><prosody rate="x-fast" pitch="low" volume="loud">Hi</prosody></speak>`},
+		{"phoneme", `<speak><phoneme alphabet="ipa" ph="təˈmɑːtəʊ">tomato</phoneme></speak>`},
+		{"entities", `<speak>AT&amp;T &lt;3 &quot;quoted&quot; &#x41;</speak>`},
+		{"comments", `<!-- note --><speak>Hi<!-- inner --></speak>`},
+		{"xml declaration", `<?xml version="1.0"?><speak>Hi</speak>`},
+	}
+	for _, tt := range valid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseSSML(tt.markup); err != nil {
+				t.Errorf("ParseSSML: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseSSMLErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		markup string
+		want   string // part of the error message
+	}{
+		{"unknown element", `<speak><audio src="x.wav"/></speak>`, "unsupported element <audio>"},
+		{"unknown element in namespace", `<speak><mstts:express-as>Hi</mstts:express-as></speak>`, "unsupported element <express-as>"},
+		{"unknown attribute", `<speak><break time="1s" onload="x"/></speak>`, `<break> does not support attribute "onload"`},
+		{"attribute of another element", `<speak><emphasis time="1s">Hi</emphasis></speak>`, `<emphasis> does not support attribute "time"`},
+		{"missing speak root", `<p>Hello</p>`, "the document must start with <speak>"},
+		{"no root at all", `Hello`, "text outside <speak>"},
+		{"only a comment", `<!-- Hello -->`, "no <speak> element"},
+		{"empty document", ``, "no <speak> element"},
+		{"text before speak", `Hi <speak>there</speak>`, "text outside <speak>"},
+		{"element after speak", `<speak>Hi</speak><speak>again</speak>`, "content after </speak>"},
+		{"text after speak", `<speak>Hi</speak> and more`, "text outside <speak>"},
+		{"nested speak", `<speak><p><speak>Hi</speak></p></speak>`, "<speak> cannot be nested"},
+		{"unclosed element", `<speak><p>Hi</speak>`, "line 1"},
+		{"unclosed speak", `<speak>Hi`, "unexpected EOF"},
+		{"bad break time", `<speak>Hi<break time="soon"/></speak>`, `invalid time "soon" on <break>`},
+		{"break time without unit", `<speak>Hi<break time="500"/></speak>`, `invalid time "500" on <break>`},
+		{"negative break time", `<speak>Hi<break time="-1s"/></speak>`, `invalid time "-1s" on <break>`},
+		{"break too long", `<speak>Hi<break time="11s"/></speak>`, "longer than 10s"},
+		{"break too long in ms", `<speak>Hi<break time="10001ms"/></speak>`, "longer than 10s"},
+		{"bad break strength", `<speak>Hi<break strength="huge"/></speak>`, `invalid strength "huge" on <break>`},
+		{"text in break", `<speak><break time="1s">Hi</break></speak>`, "<break> cannot contain text"},
+		{"element in say-as", `<speak><say-as interpret-as="digits"><emphasis>1</emphasis></say-as></speak>`, "<say-as> cannot contain <emphasis>"},
+		{"say-as without interpret-as", `<speak><say-as>123</say-as></speak>`, "missing the interpret-as attribute"},
+		{"bad say-as type", `<speak><say-as interpret-as="shell">rm</say-as></speak>`, `invalid interpret-as "shell"`},
+		{"bad prosody rate", `<speak><prosody rate="1000">Hi</prosody></speak>`, `invalid rate "1000" on <prosody>`},
+		{"bad emphasis level", `<speak><emphasis level="max">Hi</emphasis></speak>`, `invalid level "max"`},
+		{"phoneme without ph", `<speak><phoneme alphabet="ipa">tomato</phoneme></speak>`, "missing the ph attribute"},
+		{"phoneme ph with brackets", `<speak><phoneme alphabet="x-espeak" ph="]] [[rm">tomato</phoneme></speak>`, `invalid ph`},
+		{"phoneme ph with an opening bracket", `<speak><phoneme alphabet="x-espeak" ph="t[[@">tomato</phoneme></speak>`, `invalid ph`},
+		{"bad phoneme alphabet", `<speak><phoneme alphabet="arpabet" ph="T AH0">the</phoneme></speak>`, `invalid alphabet "arpabet"`},
+		{"unknown entity", `<speak>Hello &nbsp; team</speak>`, "invalid character entity &nbsp;"},
+		{"doctype", `<!DOCTYPE speak [<!ENTITY x "boom">]><speak>&x;</speak>`, "directives are not allowed"},
+		{"nothing to say", `<speak><break time="1s"/> , </speak>`, ErrEmptyText.Error()},
+		{"text too long", `<speak>` + strings.Repeat("a", MaxTextLength+1) + `</speak>`, ErrTextTooLong.Error()},
+		{"document too long", `<speak>` + strings.Repeat(`<break time="1s"/>`, maxSSMLLength/17) + `a</speak>`, "longer than"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseSSML(tt.markup)
+			if !errors.Is(err, ErrInvalidSSML) {
+				t.Fatalf("err = %v, want ErrInvalidSSML", err)
+			}
+			if !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %q, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSSMLErrorPosition(t *testing.T) {
+	_, err := ParseSSML("<speak>\n  Hello\n  <blink>there</blink>\n</speak>")
+	if err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("err = %v, want it to point at line 3", err)
+	}
+}
+
+// sampleSSML uses every supported element.
+const sampleSSML = `<speak xml:lang="en-GB"><p>Welcome to the <emphasis level="strong">stand-up</emphasis>.</p>` +
+	`<s>Room <say-as interpret-as="characters">B2</say-as>, call <say-as interpret-as="telephone">+44 20-7946</say-as></s>` +
+	`<break time="700ms"/><prosody rate="slow" pitch="+2st">Say <phoneme alphabet="x-espeak" ph="t@'meItoU">tomato</phoneme>` +
+	` or <phoneme alphabet="ipa" ph="təˈmɑːtəʊ">tomato</phoneme></prosody><break strength="weak"/>AT&amp;T &lt;3</speak>`
+
+func TestSSMLOutput(t *testing.T) {
+	doc, err := ParseSSML(sampleSSML)
+	if err != nil {
+		t.Fatalf("ParseSSML: %v", err)
+	}
+
+	wantString := `<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xml:lang="en-GB">` +
+		`<p>Welcome to the <emphasis level="strong">stand-up</emphasis>.</p>` +
+		`<s>Room <say-as interpret-as="characters">B2</say-as>, call <say-as interpret-as="telephone">+44 20-7946</say-as></s>` +
+		`<break time="700ms"/><prosody pitch="+2st" rate="slow">Say <phoneme alphabet="x-espeak" ph="t@&#39;meItoU">tomato</phoneme>` +
+		` or <phoneme alphabet="ipa" ph="təˈmɑːtəʊ">tomato</phoneme></prosody><break strength="weak"/>AT&amp;T &lt;3</speak>`
+	if got := doc.String(); got != wantString {
+		t.Errorf("String()\n got %s\nwant %s", got, wantString)
+	}
+
+	wantEspeak := `<speak version="1.0" xmlns="http://www.w3.org/2001/10/synthesis" xml:lang="en-GB">` +
+		`<p>Welcome to the <emphasis level="strong">stand-up</emphasis>.</p>` +
+		`<s>Room <say-as interpret-as="characters">B2</say-as>, call 4 4 2 0 7 9 4 6</s>` +
+		`<break time="700ms"/><prosody pitch="+2st" rate="slow">Say [[t@'meItoU]] or tomato</prosody>` +
+		`<break strength="weak"/>AT&amp;T &lt;3</speak>`
+	if got := doc.espeakMarkup(); got != wantEspeak {
+		t.Errorf("espeakMarkup()\n got %s\nwant %s", got, wantEspeak)
+	}
+
+	wantPlain := "Welcome to the stand-up.\nRoom B 2, call 4 4 2 0 7 9 4 6\nSay tomato or tomato, AT&T <3"
+	if got := doc.PlainText(); got != wantPlain {
+		t.Errorf("PlainText()\n got %q\nwant %q", got, wantPlain)
+	}
+
+	// The serialized document parses back to itself
+	again, err := ParseSSML(doc.String())
+	if err != nil || again.String() != doc.String() {
+		t.Errorf("reparsing String() = %v, %v", again, err)
+	}
+}
+
+func TestSSMLPlainTextBreaks(t *testing.T) {
+	tests := []struct {
+		markup string
+		want   string
+	}{
+		{`<speak>One<break/>two</speak>`, "One, two"},
+		{`<speak>One<break strength="none"/>two</speak>`, "Onetwo"},
+		{`<speak>One<break strength="weak"/>two</speak>`, "One, two"},
+		{`<speak>One<break strength="strong"/>two</speak>`, "One\ntwo"},
+		{`<speak>One<break time="300ms"/>two</speak>`, "One, two"},
+		{`<speak>One<break time="0.5s"/>two</speak>`, "One\ntwo"},
+		{`<speak><break time="2s"/>One</speak>`, "One"},
+		{`<speak><say-as interpret-as="spell-out">abc</say-as></speak>`, "a b c"},
+		{`<speak><say-as interpret-as="digits">12 34</say-as></speak>`, "1 2 3 4"},
+		{`<speak><say-as interpret-as="cardinal">1234</say-as></speak>`, "1234"},
+	}
+	for _, tt := range tests {
+		doc, err := ParseSSML(tt.markup)
+		if err != nil {
+			t.Errorf("ParseSSML(%s): %v", tt.markup, err)
+			continue
+		}
+		if got := doc.PlainText(); got != tt.want {
+			t.Errorf("PlainText(%s) = %q, want %q", tt.markup, got, tt.want)
+		}
+	}
+}
+
+func TestBreakDuration(t *testing.T) {
+	tests := []struct {
+		time string
+		want time.Duration
+	}{
+		{"1s", time.Second},
+		{"1.5s", 1500 * time.Millisecond},
+		{"250ms", 250 * time.Millisecond},
+		{"0.5ms", 500 * time.Microsecond},
+		{"10s", 10 * time.Second},
+		{"0s", 0},
+		{"", 0},
+	}
+	for _, tt := range tests {
+		if got := breakDuration(tt.time); got != tt.want {
+			t.Errorf("breakDuration(%q) = %v, want %v", tt.time, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package tts
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxTextLength is the longest text, in characters, accepted for one
+// utterance. That is several minutes of speech at any sensible rate.
+const MaxTextLength = 5000
+
+var (
+	ErrEmptyText    = errors.New("text is empty")
+	ErrTextTooLong  = fmt.Errorf("text is longer than %d characters", MaxTextLength)
+	ErrInvalidVoice = errors.New("invalid voice")
+)
+
+// Voice IDs reach synthesizers as command line arguments and file names, so
+// they may not start with "-" or contain path separators.
+var voicePattern = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.+:-]{0,63}$`)
+
+// SanitizeText prepares untrusted text for a synthesizer. Invalid UTF-8 and
+// control characters other than line breaks and tabs become spaces. Engines
+// only ever get text on stdin or in a request body, never through a shell,
+// so quotes, $(...) and backticks are spoken like any other characters.
+func SanitizeText(text string) (string, error) {
+	text = strings.ToValidUTF8(text, " ")
+	text = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) && r != '\n' && r != '\t' {
+			return ' '
+		}
+		return r
+	}, text)
+	text = strings.TrimSpace(text)
+
+	if text == "" {
+		return "", ErrEmptyText
+	}
+	if utf8.RuneCountInString(text) > MaxTextLength {
+		return "", ErrTextTooLong
+	}
+	return text, nil
+}
+
+// ValidateVoice rejects voice IDs that could be mistaken for options or
+// paths. An empty voice selects the engine's default and is allowed.
+func ValidateVoice(voice string) error {
+	if voice != "" && !voicePattern.MatchString(voice) {
+		return fmt.Errorf("%w: %q", ErrInvalidVoice, voice)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"meetbot-go-2/audio"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// defaultPipePath is the FIFO behind the "virtmic" source created by setup.sh.
+const defaultPipePath = "/tmp/virtmic"
+
+// micFormat is the sample format of every module-pipe-source, set up by
+// setup.sh and newVirtualMic.
+var micFormat = audio.Format{
+	SampleRate:    48000,
+	Channels:      2,
+	BitsPerSample: 16,
+	Encoding:      audio.EncodingPCM,
+}
+
+// virtualMic is a PulseAudio pipe source that a bot's browser captures as its
+// microphone. Audio written to pipePath is heard in the meeting.
+type virtualMic struct {
+	source   string // PulseAudio source name
+	pipePath string
+	moduleID string // pactl module index, empty for the mic owned by setup.sh
+
+	// mixer is the only writer to pipePath. It keeps the source fed with
+	// silence between utterances.
+	mixer  *audio.Mixer
+	speech *SpeechQueue
+}
+
+// defaultMic is the mic set up by setup.sh, shared by the default session
+// and by /generate requests made before any session exists.
+var defaultMic *virtualMic
+
+// newDefaultVirtualMic returns the mic set up by setup.sh. It is the
+// PulseAudio default source, so the browser does not need to be pointed at it.
+func newDefaultVirtualMic() *virtualMic {
+	mic := &virtualMic{
+		source:   "virtmic",
+		pipePath: defaultPipePath,
+	}
+	mic.mixer = audio.NewMixer(micFormat, mic.openPipe)
+	mic.speech = NewSpeechQueue(mic, defaultSessionID)
+	return mic
+}
+
+// newVirtualMic loads a dedicated module-pipe-source for a session, using the
+// same format as setup.sh.
+func newVirtualMic(sessionID string) (*virtualMic, error) {
+	source := "virtmic_" + sessionID
+	pipePath := "/tmp/virtmic_" + sessionID
+
+	// module-pipe-source creates the FIFO itself and fails if a stale one exists
+	if _, err := os.Stat(pipePath); err == nil {
+		os.Remove(pipePath)
+	}
+
+	out, err := exec.Command("pactl", "load-module", "module-pipe-source",
+		"source_name="+source,
+		"file="+pipePath,
+		"format=s16le",
+		"rate=48000",
+		"channels=2",
+	).Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load pipe source %s: %v", source, err)
+	}
+
+	fmt.Printf("Loaded virtual mic %s at %s\n", source, pipePath)
+
+	mic := &virtualMic{
+		source:   source,
+		pipePath: pipePath,
+		moduleID: strings.TrimSpace(string(out)),
+	}
+	mic.mixer = audio.NewMixer(micFormat, mic.openPipe)
+	mic.speech = NewSpeechQueue(mic, sessionID)
+	return mic, nil
+}
+
+// Close stops writing to the pipe and unloads the pipe source. The shared
+// setup.sh mic is left alone.
+func (m *virtualMic) Close() error {
+	if m.moduleID == "" {
+		return nil
+	}
+	m.speech.Close()
+	m.mixer.Close()
+
+	err := exec.Command("pactl", "unload-module", m.moduleID).Run()
+	if err != nil {
+		return fmt.Errorf("failed to unload pipe source %s: %v", m.source, err)
+	}
+
+	fmt.Printf("Unloaded virtual mic %s\n", m.source)
+	return nil
+}
+
+// Play sends PCM in micFormat to the mic and returns once it has been heard,
+// waiting for anything already playing. pcm is closed once it has been
+// played. Speech goes through m.speech instead.
+func (m *virtualMic) Play(ctx context.Context, pcm io.ReadCloser) (audio.Playback, error) {
+	return m.mixer.Play(ctx, pcm)
+}
+
+func (m *virtualMic) openPipe() (io.WriteCloser, error) {
+	pipe, err := openPipeNonBlocking(m.pipePath)
+	if err == unix.ENXIO {
+		// No reader on the other end of the pipe
+		return nil, fmt.Errorf("no reader available on %s", m.pipePath)
+	}
+	return pipe, err
+}
+
+func openPipeNonBlocking(path string) (*os.File, error) {
+	fd, err := unix.Open(path, unix.O_WRONLY|unix.O_NONBLOCK, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return os.NewFile(uintptr(fd), path), nil
+}
+
+// sendTestTone plays a short 440Hz sine tone through the virtual microphone
+// so the audio path can be verified without going through espeak-ng.
+func sendTestTone(ctx context.Context, mic *virtualMic) error {
+	const (
+		frequency = 440.0
+		amplitude = 0.3
+		duration  = 2 * time.Second
+	)
+	sampleRate, channels := micFormat.SampleRate, micFormat.Channels
+
+	samples := int(duration.Seconds()) * sampleRate
+	buf := make([]byte, samples*channels*2)
+	for i := 0; i < samples; i++ {
+		v := int16(amplitude * math.MaxInt16 * math.Sin(2*math.Pi*frequency*float64(i)/float64(sampleRate)))
+		for c := 0; c < channels; c++ {
+			binary.LittleEndian.PutUint16(buf[(i*channels+c)*2:], uint16(v))
+		}
+	}
+
+	fmt.Printf("Sending %v test tone (%.0fHz) to %s...\n", duration, frequency, mic.pipePath)
+
+	if _, err := mic.Play(ctx, io.NopCloser(bytes.NewReader(buf))); err != nil {
+		return fmt.Errorf("failed to play test tone: %v", err)
+	}
+
+	fmt.Println("Test tone sent to virtual microphone")
+	return nil
+}
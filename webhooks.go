@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"meetbot-go-2/bot"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	webhookMaxAttempts    = 5
+	webhookInitialBackoff = time.Second
+	webhookMaxBackoff     = 30 * time.Second
+	webhookTimeout        = 10 * time.Second
+
+	// webhookLogSize bounds the delivery log kept in memory.
+	webhookLogSize = 500
+)
+
+// defaultWebhookEvents are delivered to subscriptions that do not pick their
+// own event types.
+var defaultWebhookEvents = []bot.EventType{
+	bot.EventLoginSucceeded,
+	bot.EventLoginFailed,
+	bot.EventMeetingJoined,
+	bot.EventJoinFailed,
+	bot.EventMeetingLeft,
+	bot.EventTTSStarted,
+	bot.EventTTSFinished,
+}
+
+// Webhook is a subscription that receives matching events as signed POSTs.
+type Webhook struct {
+	ID        string          `json:"id"`
+	URL       string          `json:"url"`
+	Events    []bot.EventType `json:"events"`
+	Session   string          `json:"session,omitempty"` // only events from this session, if set
+	CreatedAt time.Time       `json:"createdAt"`
+
+	secret string
+}
+
+func (h *Webhook) matches(e bot.Event) bool {
+	if h.Session != "" && h.Session != e.Source {
+		return false
+	}
+	for _, t := range h.Events {
+		if t == e.Type {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is one entry of the delivery log. It is updated in place as
+// attempts are made.
+type WebhookDelivery struct {
+	ID         string        `json:"id"`
+	WebhookID  string        `json:"webhookId"`
+	EventID    uint64        `json:"eventId"`
+	EventType  bot.EventType `json:"eventType"`
+	Attempts   int           `json:"attempts"`
+	StatusCode int           `json:"statusCode,omitempty"`
+	LastError  string        `json:"lastError,omitempty"`
+	Delivered  bool          `json:"delivered"`
+	Pending    bool          `json:"pending"`
+	CreatedAt  time.Time     `json:"createdAt"`
+	UpdatedAt  time.Time     `json:"updatedAt"`
+}
+
+// webhookPayload is the JSON body POSTed to subscribers.
+type webhookPayload struct {
+	DeliveryID string    `json:"deliveryId"`
+	WebhookID  string    `json:"webhookId"`
+	Event      bot.Event `json:"event"`
+}
+
+// WebhookManager holds webhook subscriptions and delivers events to them.
+type WebhookManager struct {
+	mu         sync.Mutex
+	hooks      map[string]*Webhook
+	deliveries []*WebhookDelivery
+	client     *http.Client
+	backoff    time.Duration // wait before the first retry
+
+	// ctx is cancelled by Close to abandon deliveries still in progress
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewWebhookManager() *WebhookManager {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookManager{
+		hooks:   make(map[string]*Webhook),
+		client:  &http.Client{Timeout: webhookTimeout},
+		backoff: webhookInitialBackoff,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Close abandons deliveries still in progress or waiting to be retried.
+// Their log entries record the interrupted attempt.
+func (m *WebhookManager) Close() {
+	m.cancel()
+}
+
+// Run delivers events from the bus until the subscription is closed.
+func (m *WebhookManager) Run(bus *bot.EventBus) {
+	sub := bus.Subscribe(256)
+	for e := range sub.C {
+		m.dispatch(e)
+	}
+}
+
+// Add registers a subscription. An empty events list subscribes to
+// defaultWebhookEvents.
+func (m *WebhookManager) Add(rawURL, secret, session string, events []bot.EventType) (*Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid webhook url: %q", rawURL)
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("webhook secret is required")
+	}
+	if len(events) == 0 {
+		events = defaultWebhookEvents
+	}
+
+	hook := &Webhook{
+		ID:        newID(),
+		URL:       rawURL,
+		Events:    events,
+		Session:   session,
+		CreatedAt: time.Now(),
+		secret:    secret,
+	}
+
+	m.mu.Lock()
+	m.hooks[hook.ID] = hook
+	m.mu.Unlock()
+
+	log.Printf("[WEBHOOK] Registered %s -> %s for %v", hook.ID, hook.URL, hook.Events)
+	return hook, nil
+}
+
+func (m *WebhookManager) Remove(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.hooks[id]; !ok {
+		return false
+	}
+	delete(m.hooks, id)
+	return true
+}
+
+func (m *WebhookManager) List() []*Webhook {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]*Webhook, 0, len(m.hooks))
+	for _, hook := range m.hooks {
+		list = append(list, hook)
+	}
+	return list
+}
+
+// Deliveries returns a copy of the delivery log, newest first, optionally
+// limited to one webhook.
+func (m *WebhookManager) Deliveries(webhookID string) []WebhookDelivery {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	list := make([]WebhookDelivery, 0, len(m.deliveries))
+	for i := len(m.deliveries) - 1; i >= 0; i-- {
+		d := m.deliveries[i]
+		if webhookID == "" || d.WebhookID == webhookID {
+			list = append(list, *d)
+		}
+	}
+	return list
+}
+
+func (m *WebhookManager) dispatch(e bot.Event) {
+	m.mu.Lock()
+	var targets []*Webhook
+	for _, hook := range m.hooks {
+		if hook.matches(e) {
+			targets = append(targets, hook)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, hook := range targets {
+		go m.deliver(hook, e)
+	}
+}
+
+// deliver POSTs the event to one webhook, retrying with exponential backoff
+// on network errors, 5xx, 408 and 429 responses.
+func (m *WebhookManager) deliver(hook *Webhook, e bot.Event) {
+	now := time.Now()
+	delivery := &WebhookDelivery{
+		ID:        newID(),
+		WebhookID: hook.ID,
+		EventID:   e.ID,
+		EventType: e.Type,
+		Pending:   true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	m.mu.Lock()
+	m.deliveries = append(m.deliveries, delivery)
+	if len(m.deliveries) > webhookLogSize {
+		m.deliveries = m.deliveries[len(m.deliveries)-webhookLogSize:]
+	}
+	m.mu.Unlock()
+
+	body, err := json.Marshal(webhookPayload{
+		DeliveryID: delivery.ID,
+		WebhookID:  hook.ID,
+		Event:      e,
+	})
+	if err != nil {
+		m.record(delivery, 0, err, false, true)
+		return
+	}
+
+	backoff := m.backoff
+	for attempt := 1; attempt <= webhookMaxAttempts; attempt++ {
+		status, err := m.post(hook, delivery.ID, e.Type, body)
+		ok := err == nil && status >= 200 && status < 300
+		retryable := err != nil || status >= 500 || status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+		if err == nil && !ok {
+			err = fmt.Errorf("unexpected status %d", status)
+		}
+
+		last := ok || !retryable || attempt == webhookMaxAttempts || m.ctx.Err() != nil
+		m.record(delivery, status, err, ok, last)
+		if last {
+			if !ok {
+				log.Printf("[WEBHOOK] Delivery %s of %s to %s failed after %d attempts: %v",
+					delivery.ID, e.Type, hook.URL, attempt, err)
+			}
+			return
+		}
+
+		log.Printf("[WEBHOOK] Delivery %s attempt %d failed: %v, retrying in %v", delivery.ID, attempt, err, backoff)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-m.ctx.Done():
+			timer.Stop()
+			m.abandon(delivery)
+			log.Printf("[WEBHOOK] Delivery %s of %s to %s abandoned at shutdown", delivery.ID, e.Type, hook.URL)
+			return
+		}
+		backoff *= 2
+		if backoff > webhookMaxBackoff {
+			backoff = webhookMaxBackoff
+		}
+	}
+}
+
+func (m *WebhookManager) post(hook *Webhook, deliveryID string, eventType bot.EventType, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(m.ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	// Each attempt is signed afresh so a retry is not rejected as stale
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Meetbot-Event", string(eventType))
+	req.Header.Set("X-Meetbot-Delivery", deliveryID)
+	req.Header.Set("X-Meetbot-Timestamp", timestamp)
+	req.Header.Set("X-Meetbot-Signature", "sha256="+signWebhook(hook.secret, timestamp, body))
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// abandon marks a delivery that will not be retried, keeping the error of
+// its last attempt.
+func (m *WebhookManager) abandon(d *WebhookDelivery) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d.Pending = false
+	d.UpdatedAt = time.Now()
+}
+
+func (m *WebhookManager) record(d *WebhookDelivery, status int, err error, delivered, done bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	d.Attempts++
+	d.StatusCode = status
+	d.Delivered = delivered
+	d.Pending = !done
+	d.LastError = ""
+	if err != nil {
+		d.LastError = err.Error()
+	}
+	d.UpdatedAt = time.Now()
+}
+
+// signWebhook returns the hex HMAC-SHA256 of timestamp + "." + body.
+// Receivers recompute it with their copy of the secret, compare it to
+// X-Meetbot-Signature, and reject X-Meetbot-Timestamp values more than five
+// minutes from their own clock so a captured request cannot be replayed.
+func signWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseEventTypes splits a comma separated list of event types, rejecting
+// names the bot never publishes so a typo doesn't leave a silent webhook.
+func parseEventTypes(v string) ([]bot.EventType, error) {
+	var types []bot.EventType
+	for _, part := range strings.Split(v, ",") {
+		if part = strings.TrimSpace(part); part == "" {
+			continue
+		}
+		t := bot.EventType(part)
+		if !slices.Contains(bot.EventTypes, t) {
+			return nil, fmt.Errorf("unknown event type %q (known types: %s)", part, joinEventTypes(bot.EventTypes))
+		}
+		types = append(types, t)
+	}
+	return types, nil
+}
+
+func joinEventTypes(types []bot.EventType) string {
+	names := make([]string, len(types))
+	for i, t := range types {
+		names[i] = string(t)
+	}
+	return strings.Join(names, ", ")
+}
+
+var webhooks = NewWebhookManager()
+
+func webhooksHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(webhooks.List())
+
+	case http.MethodPost:
+		events, err := parseEventTypes(r.FormValue("events"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hook, err := webhooks.Add(r.FormValue("url"), r.FormValue("secret"), r.FormValue("session"), events)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(hook)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !webhooks.Remove(r.PathValue("id")) {
+		http.Error(w, "Webhook not found", http.StatusNotFound)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Webhook removed"))
+}
+
+func webhookDeliveriesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(webhooks.Deliveries(r.URL.Query().Get("webhook")))
+}
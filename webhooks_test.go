@@ -0,0 +1,216 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"meetbot-go-2/bot"
+)
+
+func TestParseEventTypes(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []bot.EventType
+		ok   bool
+	}{
+		{"", nil, true},
+		{"meeting_joined", []bot.EventType{bot.EventMeetingJoined}, true},
+		{" meeting_joined , ,state_changed ", []bot.EventType{bot.EventMeetingJoined, bot.EventStateChanged}, true},
+		{"state_change", nil, false},
+		{"meeting_joined,Meeting_Left", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := parseEventTypes(tt.in)
+		if (err == nil) != tt.ok || !slices.Equal(got, tt.want) {
+			t.Errorf("parseEventTypes(%q) = %v, %v; want %v, ok %v", tt.in, got, err, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestRegisterWebhookUnknownEvent(t *testing.T) {
+	form := url.Values{
+		"url":    {"https://example.com/hook"},
+		"secret": {"s3cret"},
+		"events": {"meeting_joined,state_change"},
+	}
+	r := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w := httptest.NewRecorder()
+	webhooksHandler(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	if !strings.Contains(w.Body.String(), `"state_change"`) {
+		t.Errorf("body = %q, want it to name the unknown event", w.Body.String())
+	}
+	if hooks := webhooks.List(); len(hooks) != 0 {
+		t.Errorf("registered %d webhooks, want none", len(hooks))
+	}
+}
+
+// webhookReceiver answers deliveries with statuses in turn, repeating the
+// last one, and records what it was sent.
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	status := rcv.statuses[min(len(rcv.requests), len(rcv.statuses)-1)]
+	rcv.requests = append(rcv.requests, r)
+	rcv.bodies = append(rcv.bodies, body)
+	w.WriteHeader(status)
+}
+
+func (rcv *webhookReceiver) count() int {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return len(rcv.requests)
+}
+
+func newTestWebhook(t *testing.T, statuses ...int) (*WebhookManager, *Webhook, *webhookReceiver) {
+	rcv := &webhookReceiver{statuses: statuses}
+	srv := httptest.NewServer(rcv)
+	t.Cleanup(srv.Close)
+
+	m := NewWebhookManager()
+	m.backoff = time.Millisecond
+	t.Cleanup(m.Close)
+	hook, err := m.Add(srv.URL, "s3cret", "", nil)
+	if err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	return m, hook, rcv
+}
+
+var testEvent = bot.Event{ID: 7, Type: bot.EventMeetingJoined, Source: "default", Time: time.Unix(1700000000, 0)}
+
+func TestWebhookSignature(t *testing.T) {
+	m, hook, rcv := newTestWebhook(t, http.StatusOK)
+
+	before := time.Now().Unix()
+	m.deliver(hook, testEvent)
+
+	if rcv.count() != 1 {
+		t.Fatalf("received %d requests, want 1", rcv.count())
+	}
+	r, body := rcv.requests[0], rcv.bodies[0]
+
+	timestamp := r.Header.Get("X-Meetbot-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || ts < before || ts > time.Now().Unix() {
+		t.Fatalf("X-Meetbot-Timestamp = %q, want the current Unix time", timestamp)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(timestamp + "." + string(body)))
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.Header.Get("X-Meetbot-Signature") != want {
+		t.Errorf("X-Meetbot-Signature = %q, want %q", r.Header.Get("X-Meetbot-Signature"), want)
+	}
+
+	var payload webhookPayload
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatalf("body is not a payload: %v", err)
+	}
+	if payload.WebhookID != hook.ID || payload.Event.ID != testEvent.ID || payload.DeliveryID != r.Header.Get("X-Meetbot-Delivery") {
+		t.Errorf("payload = %+v, headers %v", payload, r.Header)
+	}
+	if got := r.Header.Get("X-Meetbot-Event"); got != string(bot.EventMeetingJoined) {
+		t.Errorf("X-Meetbot-Event = %q, want %q", got, bot.EventMeetingJoined)
+	}
+}
+
+func TestWebhookRetry(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		attempts  int
+		delivered bool
+		lastError string
+	}{
+		{"ok", []int{200}, 1, true, ""},
+		{"5xx then ok", []int{500, 503, 204}, 3, true, ""},
+		{"rate limited", []int{429, 200}, 2, true, ""},
+		{"timeout", []int{408, 200}, 2, true, ""},
+		{"4xx not retried", []int{400}, 1, false, "unexpected status 400"},
+		{"not found not retried", []int{500, 404}, 2, false, "unexpected status 404"},
+		{"gives up", []int{500}, webhookMaxAttempts, false, "unexpected status 500"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, hook, rcv := newTestWebhook(t, tt.statuses...)
+
+			m.deliver(hook, testEvent)
+
+			if rcv.count() != tt.attempts {
+				t.Errorf("received %d requests, want %d", rcv.count(), tt.attempts)
+			}
+			deliveries := m.Deliveries(hook.ID)
+			if len(deliveries) != 1 {
+				t.Fatalf("logged %d deliveries, want 1", len(deliveries))
+			}
+			d := deliveries[0]
+			if d.Attempts != tt.attempts || d.Delivered != tt.delivered || d.Pending || d.LastError != tt.lastError {
+				t.Errorf("delivery = %+v, want %d attempts, delivered %v, error %q", d, tt.attempts, tt.delivered, tt.lastError)
+			}
+			if d.EventID != testEvent.ID || d.EventType != testEvent.Type || d.WebhookID != hook.ID {
+				t.Errorf("delivery = %+v, want it to name the event and webhook", d)
+			}
+			if want := tt.statuses[min(tt.attempts, len(tt.statuses))-1]; d.StatusCode != want {
+				t.Errorf("StatusCode = %d, want %d", d.StatusCode, want)
+			}
+
+			// Every attempt carries the same delivery ID
+			for _, r := range rcv.requests {
+				if r.Header.Get("X-Meetbot-Delivery") != d.ID {
+					t.Errorf("X-Meetbot-Delivery = %q, want %q", r.Header.Get("X-Meetbot-Delivery"), d.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestWebhookCloseStopsRetry(t *testing.T) {
+	m, hook, _ := newTestWebhook(t, http.StatusInternalServerError)
+	m.backoff = time.Hour
+
+	done := make(chan struct{})
+	go func() {
+		m.deliver(hook, testEvent)
+		close(done)
+	}()
+	// Wait for the first attempt to be logged, so Close lands in the backoff
+	for len(m.Deliveries(hook.ID)) == 0 || m.Deliveries(hook.ID)[0].Attempts == 0 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	m.Close()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("delivery kept waiting to retry after Close")
+	}
+	d := m.Deliveries(hook.ID)[0]
+	if d.Attempts != 1 || d.Pending || d.Delivered || d.LastError != "unexpected status 500" {
+		t.Errorf("delivery = %+v, want one failed attempt and no retry pending", d)
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"meetbot-go-2/bot"
+)
+
+func TestWSAccept(t *testing.T) {
+	// The worked example from RFC 6455 section 1.3
+	if got := wsAccept("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("wsAccept = %q, want %q", got, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=")
+	}
+}
+
+// dialWebSocket sends an upgrade request for path to server and returns the
+// connection and the parsed response.
+func dialWebSocket(t *testing.T, server *httptest.Server, path string, header http.Header) (net.Conn, *bufio.Reader, *http.Response) {
+	t.Helper()
+	conn, err := net.Dial("tcp", server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	if err := req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatalf("reading handshake response: %v", err)
+	}
+	return conn, br, resp
+}
+
+// clientFrame encodes a masked frame the way a browser sends it.
+func clientFrame(opcode byte, payload []byte) []byte {
+	mask := [4]byte{0x12, 0x34, 0x56, 0x78}
+	frame := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		frame = append(frame, 0x80|byte(n))
+	case n <= 0xFFFF:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(n))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(n))
+	}
+	frame = append(frame, mask[:]...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	return frame
+}
+
+// readServerFrame decodes an unmasked frame from the server.
+func readServerFrame(t *testing.T, r io.Reader) (byte, []byte) {
+	t.Helper()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	if head[0]&0x80 == 0 {
+		t.Fatal("server sent a fragmented frame")
+	}
+	if head[1]&0x80 != 0 {
+		t.Fatal("server frames must not be masked")
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(r, ext[:])
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatalf("reading frame payload: %v", err)
+	}
+	return head[0] & 0x0F, payload
+}
+
+func TestEventsWebSocket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(eventsWebSocketHandler))
+	defer server.Close()
+
+	// A page served by the bot itself
+	conn, br, resp := dialWebSocket(t, server, "/events/ws?session=ws-test", http.Header{"Origin": {server.URL}})
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("status = %s, want 101", resp.Status)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+	if !headerContains(resp.Header, "Upgrade", "websocket") || !headerContains(resp.Header, "Connection", "upgrade") {
+		t.Errorf("response headers = %v, want an upgrade to websocket", resp.Header)
+	}
+
+	// The pong also shows the handler has subscribed to events
+	conn.Write(clientFrame(wsOpPing, []byte("are you there")))
+	if op, payload := readServerFrame(t, br); op != wsOpPong || string(payload) != "are you there" {
+		t.Fatalf("got opcode %#x %q, want a pong echoing the ping", op, payload)
+	}
+
+	events.Publish(bot.Event{Type: bot.EventMeetingJoined, Source: "another-session"})
+	events.Publish(bot.Event{Type: bot.EventMeetingJoined, Source: "ws-test", Data: map[string]any{"note": strings.Repeat("x", 300)}})
+	op, payload := readServerFrame(t, br)
+	if op != wsOpText {
+		t.Fatalf("opcode = %#x, want text", op)
+	}
+	var e bot.Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		t.Fatalf("message is not an event: %v", err)
+	}
+	if e.Source != "ws-test" || e.Type != bot.EventMeetingJoined || len(e.Data["note"].(string)) != 300 {
+		t.Errorf("event = %+v, want the ws-test meeting_joined event", e)
+	}
+
+	conn.Write(clientFrame(wsOpClose, nil))
+	if op, _ := readServerFrame(t, br); op != wsOpClose {
+		t.Errorf("opcode = %#x, want close", op)
+	}
+}
+
+func TestEventsWebSocketOrigin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(eventsWebSocketHandler))
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	tests := []struct {
+		name   string
+		header http.Header
+		status int
+	}{
+		{"same origin", http.Header{"Origin": {server.URL}}, http.StatusSwitchingProtocols},
+		{"same origin, other case", http.Header{"Origin": {"HTTP://" + strings.ToUpper(host)}}, http.StatusSwitchingProtocols},
+		{"no origin", nil, http.StatusSwitchingProtocols},
+		{"other site", http.Header{"Origin": {"https://evil.example"}}, http.StatusForbidden},
+		{"other port", http.Header{"Origin": {"http://127.0.0.1:1"}}, http.StatusForbidden},
+		{"opaque origin", http.Header{"Origin": {"null"}}, http.StatusForbidden},
+		{"not a websocket", http.Header{"Upgrade": {"h2c"}}, http.StatusBadRequest},
+		{"old version", http.Header{"Sec-Websocket-Version": {"8"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, resp := dialWebSocket(t, server, "/events/ws", tt.header)
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+		})
+	}
+}
+
+func TestWSWriteFrame(t *testing.T) {
+	for _, n := range []int{0, 125, 126, 0xFFFF, 0x10000} {
+		server, client := net.Pipe()
+		c := &wsConn{conn: server, rw: bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))}
+		payload := bytes.Repeat([]byte{'a'}, n)
+
+		go func() {
+			c.writeFrame(wsOpText, payload)
+			server.Close()
+		}()
+		op, got := readServerFrame(t, client)
+		if op != wsOpText || !bytes.Equal(got, payload) {
+			t.Errorf("%d bytes: got opcode %#x and %d bytes back", n, op, len(got))
+		}
+		client.Close()
+	}
+}
+
+func TestWSReadFrame(t *testing.T) {
+	unmasked := []byte{0x80 | wsOpText, 5, 'h', 'e', 'l', 'l', 'o'}
+	tooLarge := clientFrame(wsOpText, make([]byte, wsMaxClientFrame+1))
+
+	tests := []struct {
+		name    string
+		frame   []byte
+		opcode  byte
+		payload []byte
+		ok      bool
+	}{
+		{"short", clientFrame(wsOpText, []byte("hello")), wsOpText, []byte("hello"), true},
+		{"empty", clientFrame(wsOpPing, nil), wsOpPing, []byte{}, true},
+		{"16-bit length", clientFrame(wsOpText, bytes.Repeat([]byte{7}, 300)), wsOpText, bytes.Repeat([]byte{7}, 300), true},
+		{"64-bit length", clientFrame(wsOpText, bytes.Repeat([]byte{9}, 0x10000)), wsOpText, bytes.Repeat([]byte{9}, 0x10000), true},
+		{"unmasked", unmasked, 0, nil, false},
+		{"too large", tooLarge, 0, nil, false},
+		{"truncated", clientFrame(wsOpText, []byte("hello"))[:8], 0, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &wsConn{rw: bufio.NewReadWriter(bufio.NewReader(bytes.NewReader(tt.frame)), nil)}
+			op, payload, err := c.readFrame()
+			if (err == nil) != tt.ok {
+				t.Fatalf("err = %v, want ok %v", err, tt.ok)
+			}
+			if op != tt.opcode || !bytes.Equal(payload, tt.payload) {
+				t.Errorf("got opcode %#x, %d bytes; want %#x, %d bytes", op, len(payload), tt.opcode, len(tt.payload))
+			}
+		})
+	}
+}